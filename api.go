@@ -0,0 +1,781 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+const slackAPI = "https://slack.com/api/"
+
+type apiResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// apiClient sends the Web API methods the commands use. The slack package
+// has the types but no context support, so requests are built here, bound to
+// the context of the command and sent through the transports.
+type apiClient struct {
+	token  string
+	client *http.Client
+}
+
+func newAPIClient(token string, rt http.RoundTripper) *apiClient {
+	return &apiClient{token: token, client: &http.Client{Transport: rt}}
+}
+
+// post sends a request bound to ctx and decodes the response into v, if not nil.
+func (c *apiClient) post(ctx context.Context, method string, contentType string, body io.Reader, v interface{}) error {
+	req, err := http.NewRequest("POST", slackAPI+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return decodeResponse(method, resp, v)
+}
+
+// call posts a Web API method and decodes the response into v, if not nil.
+func (c *apiClient) call(ctx context.Context, method string, values url.Values, v interface{}) error {
+	values.Set("token", c.token)
+	return c.post(ctx, method, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()), v)
+}
+
+// upload posts a Web API method as multipart with the file at path in field.
+func (c *apiClient) upload(ctx context.Context, method string, values url.Values, field string, path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	values.Set("token", c.token)
+	for key := range values {
+		if err = w.WriteField(key, values.Get(key)); err != nil {
+			return err
+		}
+	}
+	part, err := w.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, f); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.post(ctx, method, w.FormDataContentType(), &body, v)
+}
+
+// decodeResponse checks the status and ok of a response and decodes it into v, if not nil.
+func decodeResponse(method string, resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	var r apiResponse
+	if err = json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+	if !r.Ok {
+		return errors.New(r.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+func postMessageValues(channel string, text string, params slack.PostMessageParameters) (url.Values, error) {
+	values := url.Values{
+		"channel": {channel},
+		"text":    {text},
+	}
+	if params.Username != slack.DEFAULT_MESSAGE_USERNAME {
+		values.Set("username", params.Username)
+	}
+	if params.Parse != slack.DEFAULT_MESSAGE_PARSE {
+		values.Set("parse", params.Parse)
+	}
+	if params.LinkNames != slack.DEFAULT_MESSAGE_LINK_NAMES {
+		values.Set("link_names", "1")
+	}
+	if len(params.Attachments) > 0 {
+		attachments, err := json.Marshal(params.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("attachments", string(attachments))
+	}
+	if params.UnfurlLinks != slack.DEFAULT_MESSAGE_UNFURL_LINKS {
+		values.Set("unfurl_links", strconv.FormatBool(params.UnfurlLinks))
+	}
+	if params.UnfurlMedia != slack.DEFAULT_MESSAGE_UNFURL_MEDIA {
+		values.Set("unfurl_media", strconv.FormatBool(params.UnfurlMedia))
+	}
+	if params.IconURL != slack.DEFAULT_MESSAGE_ICON_URL {
+		values.Set("icon_url", params.IconURL)
+	}
+	if params.IconEmoji != slack.DEFAULT_MESSAGE_ICON_EMOJI {
+		values.Set("icon_emoji", params.IconEmoji)
+	}
+	return values, nil
+}
+
+// PostThreadMessage posts a message as a reply in the thread of threadTs,
+// broadcast also shows it in the channel.
+func (c *apiClient) PostThreadMessage(ctx context.Context, channel string, threadTs string, text string, broadcast bool, params slack.PostMessageParameters) (string, string, error) {
+	values, err := postMessageValues(channel, text, params)
+	if err != nil {
+		return "", "", err
+	}
+	values.Set("thread_ts", threadTs)
+	if broadcast {
+		values.Set("reply_broadcast", "true")
+	}
+
+	var r struct {
+		Channel string `json:"channel"`
+		Ts      string `json:"ts"`
+	}
+	if err = c.call(ctx, "chat.postMessage", values, &r); err != nil {
+		return "", "", err
+	}
+	return r.Channel, r.Ts, nil
+}
+
+// Message is slack.Message with the thread fields, which the slack client drops.
+type Message struct {
+	slack.Message
+	ThreadTs    string `json:"thread_ts,omitempty"`
+	ReplyCount  int    `json:"reply_count,omitempty"`
+	LatestReply string `json:"latest_reply,omitempty"`
+
+	Reactions []Reaction `json:"reactions,omitempty"`
+
+	// Thread is a hint added by the history commands, not part of the API.
+	Thread string `json:"thread,omitempty"`
+}
+
+// itemRef is a message, file or file comment, the target of reactions, pins and stars.
+type itemRef struct {
+	Channel     string
+	Timestamp   string
+	File        string
+	FileComment string
+}
+
+func itemRefParams(params map[string]string) itemRef {
+	return itemRef{
+		Channel:     params["channel"],
+		Timestamp:   params["ts"],
+		File:        params["file"],
+		FileComment: params["file_comment"],
+	}
+}
+
+func (r itemRef) values() url.Values {
+	values := url.Values{}
+	if len(r.Channel) > 0 {
+		values.Set("channel", r.Channel)
+	}
+	if len(r.Timestamp) > 0 {
+		values.Set("timestamp", r.Timestamp)
+	}
+	if len(r.File) > 0 {
+		values.Set("file", r.File)
+	}
+	if len(r.FileComment) > 0 {
+		values.Set("file_comment", r.FileComment)
+	}
+	return values
+}
+
+// ItemFile and ItemComment are the files and file comments in reactions, pins and stars.
+type ItemFile struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	Title     string     `json:"title"`
+	Reactions []Reaction `json:"reactions,omitempty"`
+}
+
+type ItemComment struct {
+	Id        string     `json:"id"`
+	Comment   string     `json:"comment"`
+	Reactions []Reaction `json:"reactions,omitempty"`
+}
+
+// History is slack.History with thread fields in the messages.
+type History struct {
+	Latest   string    `json:"latest"`
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"`
+}
+
+func (c *apiClient) history(ctx context.Context, method string, channel string, params slack.HistoryParameters) (*History, error) {
+	values := url.Values{
+		"channel": {channel},
+	}
+	if params.Latest != slack.DEFAULT_HISTORY_LATEST {
+		values.Set("latest", params.Latest)
+	}
+	if params.Oldest != slack.DEFAULT_HISTORY_OLDEST {
+		values.Set("oldest", params.Oldest)
+	}
+	if params.Count != slack.DEFAULT_HISTORY_COUNT {
+		values.Set("count", strconv.Itoa(params.Count))
+	}
+
+	h := new(History)
+	if err := c.call(ctx, method, values, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (c *apiClient) GetChannelHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error) {
+	return c.history(ctx, "channels.history", channel, params)
+}
+
+func (c *apiClient) GetGroupHistory(ctx context.Context, group string, params slack.HistoryParameters) (*History, error) {
+	return c.history(ctx, "groups.history", group, params)
+}
+
+func (c *apiClient) GetIMHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error) {
+	return c.history(ctx, "im.history", channel, params)
+}
+
+// GetThreadReplies returns the parent message of a thread and all its replies.
+func (c *apiClient) GetThreadReplies(ctx context.Context, channel string, ts string) ([]Message, error) {
+	var msgs []Message
+	cursor := ""
+	for {
+		var r struct {
+			Messages []Message      `json:"messages"`
+			Metadata cursorMetadata `json:"response_metadata"`
+		}
+		values := url.Values{"channel": {channel}, "ts": {ts}}
+		if err := c.call(ctx, "conversations.replies", pageValues(values, cursor, 200), &r); err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, r.Messages...)
+		if cursor = r.Metadata.NextCursor; len(cursor) == 0 {
+			return msgs, nil
+		}
+	}
+}
+
+func (c *apiClient) AuthTest(ctx context.Context) (*slack.AuthTestResponse, error) {
+	r := new(slack.AuthTestResponse)
+	if err := c.call(ctx, "auth.test", url.Values{}, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// channelResponse holds the fields of the channels, groups and im methods.
+type channelResponse struct {
+	Channel        *slack.Channel `json:"channel"`
+	Group          *slack.Group   `json:"group"`
+	Purpose        string         `json:"purpose"`
+	Topic          string         `json:"topic"`
+	NoOp           bool           `json:"no_op"`
+	AlreadyClosed  bool           `json:"already_closed"`
+	AlreadyOpen    bool           `json:"already_open"`
+	AlreadyInGroup bool           `json:"already_in_group"`
+	NotInChannel   bool           `json:"not_in_channel"`
+}
+
+func (c *apiClient) channelCall(ctx context.Context, method string, values url.Values) (*channelResponse, error) {
+	r := new(channelResponse)
+	if err := c.call(ctx, method, values, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func archivedValues(excludeArchived bool) url.Values {
+	values := url.Values{}
+	if excludeArchived {
+		values.Set("exclude_archived", "1")
+	}
+	return values
+}
+
+func (c *apiClient) ArchiveChannel(ctx context.Context, channel string) error {
+	return c.call(ctx, "channels.archive", url.Values{"channel": {channel}}, nil)
+}
+
+func (c *apiClient) CreateChannel(ctx context.Context, name string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "channels.create", url.Values{"name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) GetChannelInfo(ctx context.Context, channel string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "channels.info", url.Values{"channel": {channel}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) GetChannels(ctx context.Context, excludeArchived bool) ([]slack.Channel, error) {
+	var r struct {
+		Channels []slack.Channel `json:"channels"`
+	}
+	if err := c.call(ctx, "channels.list", archivedValues(excludeArchived), &r); err != nil {
+		return nil, err
+	}
+	return r.Channels, nil
+}
+
+func (c *apiClient) InviteUserToChannel(ctx context.Context, channel string, user string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "channels.invite", url.Values{"channel": {channel}, "user": {user}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) JoinChannel(ctx context.Context, name string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "channels.join", url.Values{"name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) KickUserFromChannel(ctx context.Context, channel string, user string) error {
+	return c.call(ctx, "channels.kick", url.Values{"channel": {channel}, "user": {user}}, nil)
+}
+
+// LeaveChannel reports whether the user was not in the channel.
+func (c *apiClient) LeaveChannel(ctx context.Context, channel string) (bool, error) {
+	r, err := c.channelCall(ctx, "channels.leave", url.Values{"channel": {channel}})
+	if err != nil {
+		return false, err
+	}
+	return r.NotInChannel, nil
+}
+
+func (c *apiClient) RenameChannel(ctx context.Context, channel string, name string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "channels.rename", url.Values{"channel": {channel}, "name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) SetChannelPurpose(ctx context.Context, channel string, purpose string) (string, error) {
+	r, err := c.channelCall(ctx, "channels.setPurpose", url.Values{"channel": {channel}, "purpose": {purpose}})
+	if err != nil {
+		return "", err
+	}
+	return r.Purpose, nil
+}
+
+func (c *apiClient) SetChannelReadMark(ctx context.Context, channel string, ts string) error {
+	return c.call(ctx, "channels.mark", url.Values{"channel": {channel}, "ts": {ts}}, nil)
+}
+
+func (c *apiClient) SetChannelTopic(ctx context.Context, channel string, topic string) (string, error) {
+	r, err := c.channelCall(ctx, "channels.setTopic", url.Values{"channel": {channel}, "topic": {topic}})
+	if err != nil {
+		return "", err
+	}
+	return r.Topic, nil
+}
+
+func (c *apiClient) UnarchiveChannel(ctx context.Context, channel string) error {
+	return c.call(ctx, "channels.unarchive", url.Values{"channel": {channel}}, nil)
+}
+
+func (c *apiClient) ArchiveGroup(ctx context.Context, group string) error {
+	return c.call(ctx, "groups.archive", url.Values{"channel": {group}}, nil)
+}
+
+// CloseGroup reports whether nothing was done and whether the group was closed already.
+func (c *apiClient) CloseGroup(ctx context.Context, group string) (bool, bool, error) {
+	r, err := c.channelCall(ctx, "groups.close", url.Values{"channel": {group}})
+	if err != nil {
+		return false, false, err
+	}
+	return r.NoOp, r.AlreadyClosed, nil
+}
+
+func (c *apiClient) CreateChildGroup(ctx context.Context, group string) (*slack.Group, error) {
+	r, err := c.channelCall(ctx, "groups.createChild", url.Values{"channel": {group}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Group, nil
+}
+
+func (c *apiClient) CreateGroup(ctx context.Context, name string) (*slack.Group, error) {
+	r, err := c.channelCall(ctx, "groups.create", url.Values{"name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Group, nil
+}
+
+func (c *apiClient) GetGroups(ctx context.Context, excludeArchived bool) ([]slack.Group, error) {
+	var r struct {
+		Groups []slack.Group `json:"groups"`
+	}
+	if err := c.call(ctx, "groups.list", archivedValues(excludeArchived), &r); err != nil {
+		return nil, err
+	}
+	return r.Groups, nil
+}
+
+// InviteUserToGroup reports whether the user was in the group already.
+func (c *apiClient) InviteUserToGroup(ctx context.Context, group string, user string) (*slack.Group, bool, error) {
+	r, err := c.channelCall(ctx, "groups.invite", url.Values{"channel": {group}, "user": {user}})
+	if err != nil {
+		return nil, false, err
+	}
+	return r.Group, r.AlreadyInGroup, nil
+}
+
+func (c *apiClient) KickUserFromGroup(ctx context.Context, group string, user string) error {
+	return c.call(ctx, "groups.kick", url.Values{"channel": {group}, "user": {user}}, nil)
+}
+
+func (c *apiClient) LeaveGroup(ctx context.Context, group string) error {
+	return c.call(ctx, "groups.leave", url.Values{"channel": {group}}, nil)
+}
+
+// OpenGroup reports whether nothing was done and whether the group was open already.
+func (c *apiClient) OpenGroup(ctx context.Context, group string) (bool, bool, error) {
+	r, err := c.channelCall(ctx, "groups.open", url.Values{"channel": {group}})
+	if err != nil {
+		return false, false, err
+	}
+	return r.NoOp, r.AlreadyOpen, nil
+}
+
+func (c *apiClient) RenameGroup(ctx context.Context, group string, name string) (*slack.Channel, error) {
+	r, err := c.channelCall(ctx, "groups.rename", url.Values{"channel": {group}, "name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) SetGroupPurpose(ctx context.Context, group string, purpose string) (string, error) {
+	r, err := c.channelCall(ctx, "groups.setPurpose", url.Values{"channel": {group}, "purpose": {purpose}})
+	if err != nil {
+		return "", err
+	}
+	return r.Purpose, nil
+}
+
+func (c *apiClient) SetGroupReadMark(ctx context.Context, group string, ts string) error {
+	return c.call(ctx, "groups.mark", url.Values{"channel": {group}, "ts": {ts}}, nil)
+}
+
+func (c *apiClient) SetGroupTopic(ctx context.Context, group string, topic string) (string, error) {
+	r, err := c.channelCall(ctx, "groups.setTopic", url.Values{"channel": {group}, "topic": {topic}})
+	if err != nil {
+		return "", err
+	}
+	return r.Topic, nil
+}
+
+func (c *apiClient) UnarchiveGroup(ctx context.Context, group string) error {
+	return c.call(ctx, "groups.unarchive", url.Values{"channel": {group}}, nil)
+}
+
+func (c *apiClient) DeleteFile(ctx context.Context, file string) error {
+	return c.call(ctx, "files.delete", url.Values{"file": {file}}, nil)
+}
+
+func (c *apiClient) GetFileInfo(ctx context.Context, file string, count int, page int) (*slack.File, []slack.Comment, *slack.Paging, error) {
+	values := url.Values{
+		"file":  {file},
+		"count": {strconv.Itoa(count)},
+		"page":  {strconv.Itoa(page)},
+	}
+
+	var r struct {
+		File     *slack.File     `json:"file"`
+		Comments []slack.Comment `json:"comments"`
+		Paging   *slack.Paging   `json:"paging"`
+	}
+	if err := c.call(ctx, "files.info", values, &r); err != nil {
+		return nil, nil, nil, err
+	}
+	return r.File, r.Comments, r.Paging, nil
+}
+
+func (c *apiClient) GetFiles(ctx context.Context, params slack.GetFilesParameters) ([]slack.File, *slack.Paging, error) {
+	values := url.Values{}
+	if params.UserId != slack.DEFAULT_FILES_USERID {
+		values.Set("user", params.UserId)
+	}
+	if params.TimestampFrom != slack.DEFAULT_FILES_TS_FROM {
+		values.Set("ts_from", strconv.FormatInt(int64(params.TimestampFrom), 10))
+	}
+	if params.TimestampTo != slack.DEFAULT_FILES_TS_TO {
+		values.Set("ts_to", strconv.FormatInt(int64(params.TimestampTo), 10))
+	}
+	if params.Types != slack.DEFAULT_FILES_TYPES {
+		values.Set("types", params.Types)
+	}
+	if params.Count != slack.DEFAULT_FILES_COUNT {
+		values.Set("count", strconv.Itoa(params.Count))
+	}
+	if params.Page != slack.DEFAULT_FILES_PAGE {
+		values.Set("page", strconv.Itoa(params.Page))
+	}
+
+	var r struct {
+		Files  []slack.File  `json:"files"`
+		Paging *slack.Paging `json:"paging"`
+	}
+	if err := c.call(ctx, "files.list", values, &r); err != nil {
+		return nil, nil, err
+	}
+	return r.Files, r.Paging, nil
+}
+
+// UploadFile uploads the file at params.File, or params.Content if it is empty.
+func (c *apiClient) UploadFile(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error) {
+	values := url.Values{}
+	if len(params.Filetype) > 0 {
+		values.Set("filetype", params.Filetype)
+	}
+	if len(params.Filename) > 0 {
+		values.Set("filename", params.Filename)
+	}
+	if len(params.Title) > 0 {
+		values.Set("title", params.Title)
+	}
+	if len(params.InitialComment) > 0 {
+		values.Set("initial_comment", params.InitialComment)
+	}
+	if channels := strings.Join(params.Channels, ","); len(channels) > 0 {
+		values.Set("channels", channels)
+	}
+
+	var r struct {
+		File *slack.File `json:"file"`
+	}
+	var err error
+	if len(params.File) > 0 {
+		err = c.upload(ctx, "files.upload", values, "file", params.File, &r)
+	} else {
+		values.Set("content", params.Content)
+		err = c.call(ctx, "files.upload", values, &r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.File, nil
+}
+
+// messageResponse holds the fields of the chat methods.
+type messageResponse struct {
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+	Text    string `json:"text"`
+}
+
+func (c *apiClient) DeleteMessage(ctx context.Context, channel string, ts string) (string, string, error) {
+	var r messageResponse
+	if err := c.call(ctx, "chat.delete", url.Values{"channel": {channel}, "ts": {ts}}, &r); err != nil {
+		return "", "", err
+	}
+	return r.Channel, r.Ts, nil
+}
+
+func (c *apiClient) PostMessage(ctx context.Context, channel string, text string, params slack.PostMessageParameters) (string, string, error) {
+	values, err := postMessageValues(channel, text, params)
+	if err != nil {
+		return "", "", err
+	}
+
+	var r messageResponse
+	if err = c.call(ctx, "chat.postMessage", values, &r); err != nil {
+		return "", "", err
+	}
+	return r.Channel, r.Ts, nil
+}
+
+func (c *apiClient) UpdateMessage(ctx context.Context, channel string, ts string, text string) (string, string, string, error) {
+	var r messageResponse
+	if err := c.call(ctx, "chat.update", url.Values{"channel": {channel}, "ts": {ts}, "text": {text}}, &r); err != nil {
+		return "", "", "", err
+	}
+	return r.Channel, r.Ts, r.Text, nil
+}
+
+func (c *apiClient) GetEmoji(ctx context.Context) (map[string]string, error) {
+	var r struct {
+		Emoji map[string]string `json:"emoji"`
+	}
+	if err := c.call(ctx, "emoji.list", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Emoji, nil
+}
+
+// CloseIMChannel reports whether nothing was done and whether the IM was closed already.
+func (c *apiClient) CloseIMChannel(ctx context.Context, channel string) (bool, bool, error) {
+	r, err := c.channelCall(ctx, "im.close", url.Values{"channel": {channel}})
+	if err != nil {
+		return false, false, err
+	}
+	return r.NoOp, r.AlreadyClosed, nil
+}
+
+func (c *apiClient) GetIMChannels(ctx context.Context) ([]slack.IM, error) {
+	var r struct {
+		IMs []slack.IM `json:"ims"`
+	}
+	if err := c.call(ctx, "im.list", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.IMs, nil
+}
+
+func (c *apiClient) MarkIMChannel(ctx context.Context, channel string, ts string) error {
+	return c.call(ctx, "im.mark", url.Values{"channel": {channel}, "ts": {ts}}, nil)
+}
+
+// OpenIMChannel reports whether nothing was done, whether the IM was open
+// already and its id.
+func (c *apiClient) OpenIMChannel(ctx context.Context, user string) (bool, bool, string, error) {
+	var r struct {
+		NoOp        bool `json:"no_op"`
+		AlreadyOpen bool `json:"already_open"`
+		Channel     struct {
+			Id string `json:"id"`
+		} `json:"channel"`
+	}
+	if err := c.call(ctx, "im.open", url.Values{"user": {user}}, &r); err != nil {
+		return false, false, "", err
+	}
+	return r.NoOp, r.AlreadyOpen, r.Channel.Id, nil
+}
+
+func searchValues(query string, params slack.SearchParameters) url.Values {
+	values := url.Values{"query": {query}}
+	if params.Sort != slack.DEFAULT_SEARCH_SORT {
+		values.Set("sort", params.Sort)
+	}
+	if params.SortDirection != slack.DEFAULT_SEARCH_SORT_DIR {
+		values.Set("sort_dir", params.SortDirection)
+	}
+	if params.Highlight {
+		values.Set("highlight", "1")
+	}
+	if params.Count != slack.DEFAULT_SEARCH_COUNT {
+		values.Set("count", strconv.Itoa(params.Count))
+	}
+	if params.Page != slack.DEFAULT_SEARCH_PAGE {
+		values.Set("page", strconv.Itoa(params.Page))
+	}
+	return values
+}
+
+func (c *apiClient) Search(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchMessages, *slack.SearchFiles, error) {
+	var r struct {
+		Messages *slack.SearchMessages `json:"messages"`
+		Files    *slack.SearchFiles    `json:"files"`
+	}
+	if err := c.call(ctx, "search.all", searchValues(query, params), &r); err != nil {
+		return nil, nil, err
+	}
+	return r.Messages, r.Files, nil
+}
+
+func (c *apiClient) SearchFiles(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchFiles, error) {
+	var r struct {
+		Files *slack.SearchFiles `json:"files"`
+	}
+	if err := c.call(ctx, "search.files", searchValues(query, params), &r); err != nil {
+		return nil, err
+	}
+	return r.Files, nil
+}
+
+func (c *apiClient) SearchMessages(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchMessages, error) {
+	var r struct {
+		Messages *slack.SearchMessages `json:"messages"`
+	}
+	if err := c.call(ctx, "search.messages", searchValues(query, params), &r); err != nil {
+		return nil, err
+	}
+	return r.Messages, nil
+}
+
+func (c *apiClient) GetUserInfo(ctx context.Context, user string) (*slack.User, error) {
+	var r struct {
+		User *slack.User `json:"user"`
+	}
+	if err := c.call(ctx, "users.info", url.Values{"user": {user}}, &r); err != nil {
+		return nil, err
+	}
+	return r.User, nil
+}
+
+func (c *apiClient) GetUserPresence(ctx context.Context, user string) (*slack.UserPresence, error) {
+	r := new(slack.UserPresence)
+	if err := c.call(ctx, "users.getPresence", url.Values{"user": {user}}, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *apiClient) GetUsers(ctx context.Context) ([]slack.User, error) {
+	var r struct {
+		Members []slack.User `json:"members"`
+	}
+	if err := c.call(ctx, "users.list", url.Values{"presence": {"1"}}, &r); err != nil {
+		return nil, err
+	}
+	return r.Members, nil
+}
+
+func (c *apiClient) SetUserAsActive(ctx context.Context) error {
+	return c.call(ctx, "users.setActive", url.Values{}, nil)
+}
+
+func (c *apiClient) SetUserPresence(ctx context.Context, presence string) error {
+	return c.call(ctx, "users.setPresence", url.Values{"presence": {presence}}, nil)
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mutating commands change state in the workspace
+var mutatingCommands = map[string]bool{
+	"channels.archive":    true,
+	"channels.create":     true,
+	"channels.invite":     true,
+	"channels.join":       true,
+	"channels.kick":       true,
+	"channels.leave":      true,
+	"channels.mark":       true,
+	"channels.rename":     true,
+	"channels.setpurpose": true,
+	"channels.settopic":   true,
+	"channels.unarchive":  true,
+
+	"conversations.archive":    true,
+	"conversations.close":      true,
+	"conversations.create":     true,
+	"conversations.invite":     true,
+	"conversations.join":       true,
+	"conversations.kick":       true,
+	"conversations.leave":      true,
+	"conversations.mark":       true,
+	"conversations.open":       true,
+	"conversations.rename":     true,
+	"conversations.setpurpose": true,
+	"conversations.settopic":   true,
+	"conversations.unarchive":  true,
+
+	"groups.archive":     true,
+	"groups.close":       true,
+	"groups.create":      true,
+	"groups.createchild": true,
+	"groups.invite":      true,
+	"groups.kick":        true,
+	"groups.leave":       true,
+	"groups.mark":        true,
+	"groups.open":        true,
+	"groups.rename":      true,
+	"groups.setpurpose":  true,
+	"groups.settopic":    true,
+	"groups.unarchive":   true,
+
+	"dnd.enddnd":    true,
+	"dnd.endsnooze": true,
+	"dnd.setsnooze": true,
+
+	"files.delete": true,
+	"files.upload": true,
+
+	"chat.delete":      true,
+	"chat.postmessage": true,
+	"chat.reply":       true,
+	"chat.update":      true,
+
+	"pins.add":    true,
+	"pins.remove": true,
+
+	"reactions.add":    true,
+	"reactions.remove": true,
+
+	"stars.add":    true,
+	"stars.remove": true,
+
+	"im.close": true,
+	"im.mark":  true,
+	"im.open":  true,
+
+	"mpim.close": true,
+	"mpim.mark":  true,
+	"mpim.open":  true,
+
+	"reminders.add":      true,
+	"reminders.complete": true,
+	"reminders.delete":   true,
+
+	"usergroups.create":       true,
+	"usergroups.disable":      true,
+	"usergroups.enable":       true,
+	"usergroups.rotate":       true,
+	"usergroups.update":       true,
+	"usergroups.users.update": true,
+
+	"users.profile.set": true,
+	"users.setactive":   true,
+	"users.setphoto":    true,
+	"users.setpresence": true,
+}
+
+func isMutating(cmd string) bool {
+	return mutatingCommands[strings.ToLower(cmd)]
+}
+
+// readOnlyCommands are the only commands allowed in read-only mode, anything
+// new is refused until it is known not to change the workspace.
+var readOnlyCommands = map[string]bool{
+	"audit.show": true,
+	"auth.test":  true,
+
+	"channels.history": true,
+	"channels.info":    true,
+	"channels.list":    true,
+
+	"conversations.history": true,
+	"conversations.info":    true,
+	"conversations.list":    true,
+	"conversations.members": true,
+
+	"groups.history": true,
+	"groups.list":    true,
+
+	"im.history": true,
+	"im.list":    true,
+
+	"mpim.history": true,
+	"mpim.list":    true,
+
+	"dnd.info":     true,
+	"dnd.teaminfo": true,
+
+	"drafts.list": true,
+
+	"emoji.list": true,
+
+	"files.info": true,
+	"files.list": true,
+
+	"local.search": true,
+
+	"pins.list": true,
+
+	"reactions.get":  true,
+	"reactions.list": true,
+
+	"reminders.info": true,
+	"reminders.list": true,
+
+	"search.all":      true,
+	"search.files":    true,
+	"search.messages": true,
+
+	"stars.export": true,
+	"stars.list":   true,
+
+	"team.accesslogs":   true,
+	"team.billableinfo": true,
+	"team.info":         true,
+
+	"thread.show": true,
+
+	"usergroups.list":       true,
+	"usergroups.users.list": true,
+
+	"users.getpresence": true,
+	"users.info":        true,
+	"users.list":        true,
+	"users.profile.get": true,
+}
+
+func isReadOnly(cmd string) bool {
+	return readOnlyCommands[strings.ToLower(cmd)]
+}
+
+type auditEntry struct {
+	Time      time.Time         `json:"time"`
+	Workspace string            `json:"workspace,omitempty"`
+	SlackUser string            `json:"slack_user,omitempty"`
+	LocalUser string            `json:"local_user,omitempty"`
+	Command   string            `json:"command"`
+	Params    map[string]string `json:"params"`
+	Result    map[string]string `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type auditLog struct {
+	path string
+
+	identified bool
+	workspace  string
+	slackUser  string
+	localUser  string
+}
+
+func newAuditLog(path string) *auditLog {
+	return &auditLog{path: path}
+}
+
+// identify looks up who is running commands, the first time something is recorded.
+func (l *auditLog) identify(s *Slack) {
+	if l.identified {
+		return
+	}
+
+	if u, err := user.Current(); err == nil {
+		l.localUser = u.Username
+	}
+
+	// auth.test runs on its own context, the one of the recorded command
+	// may be canceled already
+	if resp, err := s.s.AuthTest(context.Background()); err == nil && resp != nil {
+		l.workspace = resp.Team
+		l.slackUser = resp.UserId
+		l.identified = true
+	}
+}
+
+func (l *auditLog) record(s *Slack, cmd string, params map[string]string, v interface{}, err error) error {
+	l.identify(s)
+
+	entry := auditEntry{
+		Time:      time.Now(),
+		Workspace: l.workspace,
+		SlackUser: l.slackUser,
+		LocalUser: l.localUser,
+		Command:   cmd,
+		Params:    params,
+		Result:    resultIDs(v),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// resultIDs picks the timestamps and ids out of a command result.
+func resultIDs(v interface{}) map[string]string {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var m map[string]interface{}
+	if json.Unmarshal(data, &m) != nil {
+		return nil
+	}
+
+	ids := make(map[string]string)
+	for key, value := range m {
+		switch value := value.(type) {
+		case string:
+			if key == "ts" || key == "channel" || key == "id" {
+				ids[key] = value
+			}
+		case map[string]interface{}:
+			if id, ok := value["id"].(string); ok {
+				ids[key] = id
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
+
+func (s *Slack) audit(cmd string, params map[string]string, v interface{}, err error) {
+	if s.auditLog == nil {
+		return
+	}
+	if err := s.auditLog.record(s, cmd, params, v, err); err != nil {
+		fmt.Printf("audit log err: %s\n", err.Error())
+	}
+}
+
+func (s *Slack) handleAudit(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "show":
+		if s.auditLog == nil {
+			return nil, fmt.Errorf("audit log is not enabled")
+		}
+
+		since, err := parseDateParam(params["since"])
+		if err != nil {
+			return nil, err
+		}
+
+		// entries keep resolved ids, so match them with ids too
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := s.auditLog.query(auditQuery{
+			Command: strings.ToLower(params["command"]),
+			Channel: channel,
+			User:    user,
+			Since:   since,
+			Count:   getIntParam(params, "count", 20),
+		})
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"entries": entries,
+		}
+	default:
+		return nil, fmt.Errorf("invalid audit action %s", action)
+	}
+
+	return v, err
+}
+
+type auditQuery struct {
+	Command string
+	Channel string
+	User    string
+	Since   float64
+	Count   int
+}
+
+func (q auditQuery) match(e *auditEntry) bool {
+	if len(q.Command) > 0 && !strings.HasPrefix(strings.ToLower(e.Command), q.Command) {
+		return false
+	}
+	if len(q.Channel) > 0 && e.Params["channel"] != q.Channel {
+		return false
+	}
+	if len(q.User) > 0 && e.SlackUser != q.User && e.LocalUser != q.User {
+		return false
+	}
+	if q.Since > 0 && float64(e.Time.Unix()) < q.Since {
+		return false
+	}
+	return true
+}
+
+// query returns the latest count entries matching q, oldest first.
+func (l *auditLog) query(q auditQuery) ([]*auditEntry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return []*auditEntry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []*auditEntry{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		e := new(auditEntry)
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", l.path, n, err.Error())
+		}
+		if q.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Count > 0 && len(entries) > q.Count {
+		entries = entries[len(entries)-q.Count:]
+	}
+	return entries, nil
+}
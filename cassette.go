@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const scrubbedToken = "SCRUBBED"
+
+// interaction is one recorded Web API request and its response.
+type interaction struct {
+	Method      string     `json:"method"`
+	Params      url.Values `json:"params"`
+	Status      int        `json:"status"`
+	ContentType string     `json:"content_type,omitempty"`
+	RetryAfter  string     `json:"retry_after,omitempty"`
+	Body        string     `json:"body"`
+}
+
+func newInteraction(req *http.Request) (*interaction, error) {
+	p, err := readRequestParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	params := p.form
+	if _, ok := params["token"]; ok {
+		params.Set("token", scrubbedToken)
+	}
+
+	return &interaction{
+		Method: path.Base(req.URL.Path),
+		Params: params,
+	}, nil
+}
+
+func (i *interaction) match(other *interaction) bool {
+	return i.Method == other.Method && reflect.DeepEqual(i.Params, other.Params)
+}
+
+func (i *interaction) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	if len(i.ContentType) > 0 {
+		header.Set("Content-Type", i.ContentType)
+	}
+	if len(i.RetryAfter) > 0 {
+		header.Set("Retry-After", i.RetryAfter)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+}
+
+// recordTransport appends every request and response to a cassette file,
+// one JSON interaction per line, with the token scrubbed.
+type recordTransport struct {
+	base http.RoundTripper
+
+	m sync.Mutex
+	f *os.File
+}
+
+func newRecordTransport(base http.RoundTripper, name string) (*recordTransport, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &recordTransport{base: base, f: f}, nil
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	i, err := newInteraction(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	i.Status = resp.StatusCode
+	i.ContentType = resp.Header.Get("Content-Type")
+	i.RetryAfter = resp.Header.Get("Retry-After")
+	i.Body = string(body)
+
+	data, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if _, err = t.f.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replayTransport answers requests from a cassette file without network.
+// Each recorded interaction is used once, in the recorded order.
+type replayTransport struct {
+	m            sync.Mutex
+	interactions []*interaction
+	used         []bool
+}
+
+func newReplayTransport(name string) (*replayTransport, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &replayTransport{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		i := new(interaction)
+		if err := json.Unmarshal(scanner.Bytes(), i); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", name, n, err.Error())
+		}
+		t.interactions = append(t.interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	t.used = make([]bool, len(t.interactions))
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	want, err := newInteraction(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	for n, i := range t.interactions {
+		if !t.used[n] && i.match(want) {
+			t.used[n] = true
+			return i.response(req), nil
+		}
+	}
+	return nil, fmt.Errorf("no recorded response for %s %s", want.Method, want.Params.Encode())
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"github.com/nlopes/slack"
+)
+
+// slackClient is the part of the slack Web API the commands use, so handlers
+// can run against alternate backends like fakes, caches or export archives.
+type slackClient interface {
+	AuthTest(ctx context.Context) (*slack.AuthTestResponse, error)
+
+	ArchiveChannel(ctx context.Context, channel string) error
+	CreateChannel(ctx context.Context, name string) (*slack.Channel, error)
+	GetChannelHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error)
+	GetChannelInfo(ctx context.Context, channel string) (*slack.Channel, error)
+	GetChannels(ctx context.Context, excludeArchived bool) ([]slack.Channel, error)
+	InviteUserToChannel(ctx context.Context, channel string, user string) (*slack.Channel, error)
+	JoinChannel(ctx context.Context, name string) (*slack.Channel, error)
+	KickUserFromChannel(ctx context.Context, channel string, user string) error
+	LeaveChannel(ctx context.Context, channel string) (bool, error)
+	RenameChannel(ctx context.Context, channel string, name string) (*slack.Channel, error)
+	SetChannelPurpose(ctx context.Context, channel string, purpose string) (string, error)
+	SetChannelReadMark(ctx context.Context, channel string, ts string) error
+	SetChannelTopic(ctx context.Context, channel string, topic string) (string, error)
+	UnarchiveChannel(ctx context.Context, channel string) error
+
+	ArchiveGroup(ctx context.Context, group string) error
+	CloseGroup(ctx context.Context, group string) (bool, bool, error)
+	CreateChildGroup(ctx context.Context, group string) (*slack.Group, error)
+	CreateGroup(ctx context.Context, name string) (*slack.Group, error)
+	GetGroupHistory(ctx context.Context, group string, params slack.HistoryParameters) (*History, error)
+	GetGroups(ctx context.Context, excludeArchived bool) ([]slack.Group, error)
+	InviteUserToGroup(ctx context.Context, group string, user string) (*slack.Group, bool, error)
+	KickUserFromGroup(ctx context.Context, group string, user string) error
+	LeaveGroup(ctx context.Context, group string) error
+	OpenGroup(ctx context.Context, group string) (bool, bool, error)
+	RenameGroup(ctx context.Context, group string, name string) (*slack.Channel, error)
+	SetGroupPurpose(ctx context.Context, group string, purpose string) (string, error)
+	SetGroupReadMark(ctx context.Context, group string, ts string) error
+	SetGroupTopic(ctx context.Context, group string, topic string) (string, error)
+	UnarchiveGroup(ctx context.Context, group string) error
+
+	ArchiveConversation(ctx context.Context, channel string) error
+	CloseConversation(ctx context.Context, channel string) error
+	CreateConversation(ctx context.Context, name string, private bool) (*Conversation, error)
+	GetConversationHistory(ctx context.Context, channel string, latest string, oldest string, cursor string, limit int) (*History, string, error)
+	GetConversationInfo(ctx context.Context, channel string) (*Conversation, error)
+	GetConversationMembers(ctx context.Context, channel string, cursor string, limit int) ([]string, string, error)
+	InviteUsersToConversation(ctx context.Context, channel string, users []string) (*Conversation, error)
+	JoinConversation(ctx context.Context, channel string) (*Conversation, error)
+	KickUserFromConversation(ctx context.Context, channel string, user string) error
+	LeaveConversation(ctx context.Context, channel string) error
+	ListConversations(ctx context.Context, types string, excludeArchived bool, cursor string, limit int) ([]Conversation, string, error)
+	MarkConversation(ctx context.Context, channel string, ts string) error
+	OpenConversation(ctx context.Context, users []string) (*Conversation, error)
+	RenameConversation(ctx context.Context, channel string, name string) (*Conversation, error)
+	SetConversationPurpose(ctx context.Context, channel string, purpose string) (*Conversation, error)
+	SetConversationTopic(ctx context.Context, channel string, topic string) (*Conversation, error)
+	UnarchiveConversation(ctx context.Context, channel string) error
+
+	DeleteFile(ctx context.Context, file string) error
+	GetFileInfo(ctx context.Context, file string, count int, page int) (*slack.File, []slack.Comment, *slack.Paging, error)
+	GetFiles(ctx context.Context, params slack.GetFilesParameters) ([]slack.File, *slack.Paging, error)
+	UploadFile(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error)
+
+	DeleteMessage(ctx context.Context, channel string, ts string) (string, string, error)
+	PostMessage(ctx context.Context, channel string, text string, params slack.PostMessageParameters) (string, string, error)
+	PostThreadMessage(ctx context.Context, channel string, threadTs string, text string, broadcast bool, params slack.PostMessageParameters) (string, string, error)
+	UpdateMessage(ctx context.Context, channel string, ts string, text string) (string, string, string, error)
+
+	EndDND(ctx context.Context) error
+	EndSnooze(ctx context.Context) (*DNDStatus, error)
+	GetDNDInfo(ctx context.Context, user string) (*DNDStatus, error)
+	GetDNDTeamInfo(ctx context.Context, users []string) (map[string]*DNDStatus, error)
+	SetSnooze(ctx context.Context, minutes int) (*DNDStatus, error)
+
+	GetEmoji(ctx context.Context) (map[string]string, error)
+
+	CloseIMChannel(ctx context.Context, channel string) (bool, bool, error)
+	GetIMChannels(ctx context.Context) ([]slack.IM, error)
+	GetIMHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error)
+	MarkIMChannel(ctx context.Context, channel string, ts string) error
+	OpenIMChannel(ctx context.Context, user string) (bool, bool, string, error)
+
+	CloseMpIM(ctx context.Context, channel string) error
+	GetMpIMHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error)
+	GetMpIMs(ctx context.Context) ([]MpIM, error)
+	MarkMpIM(ctx context.Context, channel string, ts string) error
+	OpenMpIM(ctx context.Context, users []string) (*MpIM, error)
+
+	GetAccessLogs(ctx context.Context, count int, page int, before int64) ([]AccessLog, *slack.Paging, error)
+	GetBillableInfo(ctx context.Context, user string) (map[string]BillableInfo, error)
+	GetTeamInfo(ctx context.Context) (*TeamInfo, error)
+	GetTeamProfileFields(ctx context.Context) ([]TeamProfileField, error)
+
+	CreateUsergroup(ctx context.Context, fields usergroupFields) (*Usergroup, error)
+	DisableUsergroup(ctx context.Context, id string) (*Usergroup, error)
+	EnableUsergroup(ctx context.Context, id string) (*Usergroup, error)
+	ListUsergroups(ctx context.Context, includeDisabled bool, includeUsers bool) ([]Usergroup, error)
+	ListUsergroupUsers(ctx context.Context, id string) ([]string, error)
+	UpdateUsergroup(ctx context.Context, id string, fields usergroupFields) (*Usergroup, error)
+	UpdateUsergroupUsers(ctx context.Context, id string, users []string) (*Usergroup, error)
+
+	GetUserProfile(ctx context.Context, user string, includeLabels bool) (*UserProfile, error)
+	SetUserPhoto(ctx context.Context, path string, cropX int, cropY int, cropW int) error
+	SetUserProfile(ctx context.Context, user string, profile map[string]interface{}) (*UserProfile, error)
+
+	Search(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchMessages, *slack.SearchFiles, error)
+	SearchFiles(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchFiles, error)
+	SearchMessages(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchMessages, error)
+
+	AddReminder(ctx context.Context, text string, when string, user string) (*Reminder, error)
+	CompleteReminder(ctx context.Context, id string) error
+	DeleteReminder(ctx context.Context, id string) error
+	GetReminder(ctx context.Context, id string) (*Reminder, error)
+	ListReminders(ctx context.Context) ([]Reminder, error)
+
+	AddPin(ctx context.Context, channel string, item itemRef) error
+	ListPins(ctx context.Context, channel string) ([]PinnedItem, error)
+	RemovePin(ctx context.Context, channel string, item itemRef) error
+
+	AddReaction(ctx context.Context, name string, item itemRef) error
+	GetReactions(ctx context.Context, item itemRef, full bool) (*ReactedItem, error)
+	ListReactions(ctx context.Context, user string, count int, page int, full bool) ([]ReactedItem, *slack.Paging, error)
+	RemoveReaction(ctx context.Context, name string, item itemRef) error
+
+	AddStar(ctx context.Context, item itemRef) error
+	GetStarred(ctx context.Context, params slack.StarsParameters) ([]slack.StarredItem, *slack.Paging, error)
+	RemoveStar(ctx context.Context, item itemRef) error
+
+	GetThreadReplies(ctx context.Context, channel string, ts string) ([]Message, error)
+
+	GetUserInfo(ctx context.Context, user string) (*slack.User, error)
+	GetUserPresence(ctx context.Context, user string) (*slack.UserPresence, error)
+	GetUsers(ctx context.Context) ([]slack.User, error)
+	SetUserAsActive(ctx context.Context) error
+	SetUserPresence(ctx context.Context, presence string) error
+}
+
+var _ slackClient = (*apiClient)(nil)
@@ -1,6 +1,13 @@
 package main
 
 var helpCommands = [][]string{
+	[]string{":confirm", "on|off", "ask before running destructive commands like chat.delete, default is on unless -yes is given"},
+	[]string{":edit", "[command]", "edit the command, or the previous one, in $VISUAL or $EDITOR and run it"},
+	[]string{":multiline", "on|off", "wrap long input over several rows instead of scrolling, default is off unless -multiline is given"},
+
+	[]string{"audit.show", "[command] [channel] [user] [since] [count]",
+		"show mutating commands from the local audit log, command is a prefix like chat. or channels.archive, user is @name, a slack user id or a local user name, since is YYYY-MM-DD or timestamp, default count is 20"},
+
 	[]string{"auth.test", "", ""},
 
 	[]string{"channels.archive", "channel", ""},
@@ -18,6 +25,25 @@ var helpCommands = [][]string{
 	[]string{"channels.setTopic", "channel topic", ""},
 	[]string{"channels.unarchive", "channel", ""},
 
+	[]string{"conversations.archive", "channel", "conversations.* work for public and private channels, IMs and multi-person IMs, channel is #name or id"},
+	[]string{"conversations.close", "channel", ""},
+	[]string{"conversations.create", "name [is_private]", ""},
+	[]string{"conversations.history", "channel [latest] [oldest] [limit] [cursor] [all]",
+		"limit is the page size, default is 100, cursor is the next_cursor of the previous page, all=true fetches every following page"},
+	[]string{"conversations.info", "channel", ""},
+	[]string{"conversations.invite", "channel users", "users is a comma separated list of @names or user ids"},
+	[]string{"conversations.join", "channel", ""},
+	[]string{"conversations.kick", "channel user", ""},
+	[]string{"conversations.leave", "channel", ""},
+	[]string{"conversations.list", "[types] [exclude_archived] [limit] [cursor] [all]", "types is a comma separated list of public, private, im and mpim, default is public"},
+	[]string{"conversations.mark", "channel ts", ""},
+	[]string{"conversations.members", "channel [limit] [cursor] [all]", ""},
+	[]string{"conversations.open", "users", "open an IM with one user, or a multi-person IM with several"},
+	[]string{"conversations.rename", "channel name", ""},
+	[]string{"conversations.setPurpose", "channel purpose", ""},
+	[]string{"conversations.setTopic", "channel topic", ""},
+	[]string{"conversations.unarchive", "channel", ""},
+
 	[]string{"groups.archive", "channel", ""},
 	[]string{"groups.close", "channel", ""},
 	[]string{"groups.create", "name", ""},
@@ -39,11 +65,23 @@ var helpCommands = [][]string{
 	[]string{"files.list", "[user] [ts_from] [ts_to] [types] [count] [page]", ""},
 	[]string{"files.upload", "[file] [content] [filetype] [filename] [title] [initial_comment] [channels]", ""},
 
+	[]string{"chat.compose", "channel [thread_ts] [draft]",
+		"write the message in $VISUAL or $EDITOR, preview it and confirm before posting, an abandoned message is saved as a draft, draft is an id from drafts.list"},
 	[]string{"chat.delete", "ts channel", ""},
-	[]string{"chat.postMessage", "channel text [username] [parse] [link_names] [attachments] [unfurl_links] [unfurl_media] [icon_url] [icon_emoji]",
-		"attachments is a json format string"},
+	[]string{"chat.postMessage", "channel text [username] [parse] [link_names] [attachments] [unfurl_links] [unfurl_media] [icon_url] [icon_emoji] [thread_ts] [reply_broadcast]",
+		"attachments is a json format string, thread_ts posts a reply in that thread, reply_broadcast=true also shows the reply in the channel"},
+	[]string{"chat.reply", "channel ts text [broadcast]", "reply in the thread of message ts, broadcast=true also shows the reply in the channel"},
 	[]string{"chat.update", "ts channel text", ""},
 
+	[]string{"dnd.endDnd", "", "end the current DND session"},
+	[]string{"dnd.endSnooze", "", ""},
+	[]string{"dnd.info", "[user]", "DND status of user, @name or user id, default is your token user, active tells whether notifications are paused now"},
+	[]string{"dnd.setSnooze", "minutes", "pause notifications for minutes"},
+	[]string{"dnd.teamInfo", "[users]", "DND status of a comma separated list of @names or user ids"},
+
+	[]string{"drafts.delete", "id", ""},
+	[]string{"drafts.list", "", "messages abandoned in chat.compose"},
+
 	[]string{"emoji.list", "", ""},
 
 	[]string{"im.close", "channel", ""},
@@ -52,14 +90,67 @@ var helpCommands = [][]string{
 	[]string{"im.mark", "channel ts", "ts is a timestamp"},
 	[]string{"im.open", "user", ""},
 
+	[]string{"local.search", "[query] [from] [in] [after] [before] [sort] [sort_dir] [highlight] [count] [page]",
+		"search history fetched by *.history, from is @name or user id, in is #name or channel id, after and before are YYYY-MM-DD or timestamp, without query the filtered messages are listed newest first"},
+
+	[]string{"mpim.close", "channel", ""},
+	[]string{"mpim.history", "channel [latest] [oldest] [count]", "latest is a timestamp, default is now, oldest default is 0"},
+	[]string{"mpim.list", "", ""},
+	[]string{"mpim.mark", "channel ts", "ts is a timestamp"},
+	[]string{"mpim.open", "users", "users is a comma separated list of @names or user ids, you are always a member"},
+
+	[]string{"pins.add", "channel [ts] [file] [file_comment]", "pin a message by ts, or a file, or a file comment to channel"},
+	[]string{"pins.list", "channel", "pinned items of channel with a preview"},
+	[]string{"pins.remove", "channel [ts] [file] [file_comment]", ""},
+
+	[]string{"reactions.add", "name [channel ts] [file] [file_comment]", "name is an emoji name like white_check_mark, react to a message by channel and ts, or a file, or a file comment"},
+	[]string{"reactions.get", "[channel ts] [file] [file_comment] [full]", "reactions of a message, file or file comment, full=true returns all users"},
+	[]string{"reactions.list", "[user] [count] [page] [full]", "items reacted to by user, @name or user id, default is your token user, default count is 100 and page is 1"},
+	[]string{"reactions.remove", "name [channel ts] [file] [file_comment]", ""},
+
+	[]string{"reminders.add", "text time [user]",
+		"time is like \"in 20 minutes\", \"tomorrow 9am\", \"friday at 17:30\", 1730, 2026-05-01 or a 10 digit unix time, in local time, others like \"every weekday at 10:00\" are parsed by slack, user is @name or user id, default is you"},
+	[]string{"reminders.complete", "reminder", "reminder is an id from reminders.list"},
+	[]string{"reminders.delete", "reminder", ""},
+	[]string{"reminders.info", "reminder", ""},
+	[]string{"reminders.list", "[table]", "reminders, table=true shows a table with due times in local time"},
+
 	[]string{"search.all", "query [sort] [sort_dir] [highlight] [count] [page]", "sort is score or timestamp, default is score, sort_dir is asc or desc, default is desc, pass 1 to enable highlight"},
 	[]string{"search.files", "query [sort] [sort_dir] [highlight] [count] [page]", "sort is score or timestamp, default is score, sort_dir is asc or desc, default is desc, pass 1 to enable highlight"},
 	[]string{"search.messages", "query [sort] [sort_dir] [highlight] [count] [page]", "sort is score or timestamp, default is score, sort_dir is asc or desc, default is desc, pass 1 to enable highlight"},
+	[]string{"stars.add", "[channel] [ts] [file] [file_comment]", "star a message by channel and ts, a file, a file comment, or a channel"},
+	[]string{"stars.export", "[user] [output]", "write all starred items as a Markdown list of permalinks, default user is your token user, default output is stars.md"},
 	[]string{"stars.list", "[user] [count] [page]", "default user is your token user, default count is 100 and page is 1"},
+	[]string{"stars.remove", "[channel] [ts] [file] [file_comment]", ""},
+
+	[]string{"team.accessLogs", "[user] [since] [before] [count] [page] [table]",
+		"logins by IP and user agent, user is @name or user id, since and before are YYYY-MM-DD or timestamp, user or since walk every page of 1000 instead of page and count, table=true shows a table, needs a paid plan and an admin token"},
+	[]string{"team.billableInfo", "[user]", "billing state of user, default is every user"},
+	[]string{"team.info", "", ""},
+
+	[]string{"thread.show", "channel ts", "the parent message of a thread and all its replies"},
+
+	[]string{"status", "[:emoji:] [text] [expiration] [user] [dry_run] [timeout]", "shortcut for users.profile.set, like status :lunch: \"at lunch\" 1h, no args clear the status"},
+
+	[]string{"undo", "", "revert the last chat.postMessage, chat.reply, chat.update, reactions.add, reactions.remove, pins.add, pins.remove, stars.add, stars.remove, reminders.add, users.profile.set, status, dnd.setSnooze, dnd.endSnooze, usergroups.rotate, usergroups.users.update, usergroups.disable, usergroups.enable, archive, kick, setTopic, setPurpose or rename"},
+
+	[]string{"usergroups.create", "name [handle] [description] [channels]", "channels is a comma separated list of #names or ids the members join by default"},
+	[]string{"usergroups.disable", "usergroup", "usergroup is an id, @handle, or handle= instead"},
+	[]string{"usergroups.enable", "usergroup", ""},
+	[]string{"usergroups.list", "[include_disabled] [include_users]", ""},
+	[]string{"usergroups.rotate", "handle users",
+		"replace all the users of user group handle at once and show the diff, users is a comma separated list of @names or user ids, asks first unless -yes"},
+	[]string{"usergroups.update", "usergroup [name] [handle] [description] [channels]", ""},
+	[]string{"usergroups.users.list", "usergroup", ""},
+	[]string{"usergroups.users.update", "usergroup users", "users is a comma separated list of @names or user ids, they replace the current ones"},
 
-	[]string{"users.getPresence", "user", ""},
+	[]string{"users.getPresence", "user", "presence with the DND status, when the token can read it"},
 	[]string{"users.info", "user", ""},
 	[]string{"users.list", "", ""},
+	[]string{"users.profile.get", "[user] [include_labels]", "default user is your token user, include_labels=true adds the labels of custom fields"},
+	[]string{"users.profile.set", "[user] [real_name] [display_name] [first_name] [last_name] [title] [phone] [status_text] [status_emoji] [status_expiration] [field.<label>]",
+		"status_expiration is a duration like 1h, YYYY-MM-DD or unix time, 0 never expires, field.<label> sets a custom field by label or id, user needs an admin token"},
 	[]string{"users.setActive", "", ""},
+	[]string{"users.setPhoto", "image [crop_x] [crop_y] [crop_w]", "image is a local file, crop_w crops a square at crop_x and crop_y"},
 	[]string{"users.setPresence", "presence", "presence is auto or away"},
 }
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+const (
+	textMarker        = "--- text ---"
+	attachmentsMarker = "--- attachments ---"
+)
+
+// draft is a message written with chat.compose but not sent.
+type draft struct {
+	ID          int       `json:"id"`
+	Time        time.Time `json:"time"`
+	Channel     string    `json:"channel"`
+	ThreadTs    string    `json:"thread_ts,omitempty"`
+	Text        string    `json:"text"`
+	Attachments string    `json:"attachments,omitempty"`
+}
+
+func (d *draft) isEmpty() bool {
+	return len(d.Text) == 0 && len(d.Attachments) == 0
+}
+
+// draftStore keeps drafts in a JSON file.
+type draftStore struct {
+	path string
+}
+
+func newDraftStore(path string) *draftStore {
+	return &draftStore{path: path}
+}
+
+func (ds *draftStore) list() ([]*draft, error) {
+	data, err := ioutil.ReadFile(ds.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var drafts []*draft
+	if err = json.Unmarshal(data, &drafts); err != nil {
+		return nil, fmt.Errorf("load drafts %s: %s", ds.path, err.Error())
+	}
+	return drafts, nil
+}
+
+func (ds *draftStore) write(drafts []*draft) error {
+	if err := os.MkdirAll(filepath.Dir(ds.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(drafts, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp := ds.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ds.path)
+}
+
+func (ds *draftStore) get(id int) (*draft, error) {
+	drafts, err := ds.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range drafts {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("draft %d not found", id)
+}
+
+// save adds d, or replaces the draft with the same id, a new draft gets the next id.
+func (ds *draftStore) save(d *draft) error {
+	drafts, err := ds.list()
+	if err != nil {
+		return err
+	}
+
+	d.Time = time.Now()
+	if d.ID == 0 {
+		for _, old := range drafts {
+			if old.ID > d.ID {
+				d.ID = old.ID
+			}
+		}
+		d.ID++
+		return ds.write(append(drafts, d))
+	}
+
+	for i, old := range drafts {
+		if old.ID == d.ID {
+			drafts[i] = d
+			return ds.write(drafts)
+		}
+	}
+	return ds.write(append(drafts, d))
+}
+
+func (ds *draftStore) remove(id int) error {
+	drafts, err := ds.list()
+	if err != nil {
+		return err
+	}
+	for i, d := range drafts {
+		if d.ID == id {
+			return ds.write(append(drafts[:i], drafts[i+1:]...))
+		}
+	}
+	return fmt.Errorf("draft %d not found", id)
+}
+
+func composeTemplate(to string, d *draft) string {
+	return fmt.Sprintf(`# Message to %s, lines starting with # above the text are ignored.
+# Write the text below the text line and save, leave it empty to cancel.
+# Attachments are a JSON array, like [{"text": "done", "color": "good"}].
+thread_ts=%s
+%s
+%s
+%s
+%s
+`, to, d.ThreadTs, textMarker, d.Text, attachmentsMarker, d.Attachments)
+}
+
+// parseCompose reads the thread ts, text and attachments of an edited template into d.
+func parseCompose(text string, d *draft) error {
+	var section string
+	var body, attachments []string
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimRight(l, "\r")
+		switch strings.TrimSpace(l) {
+		case textMarker, attachmentsMarker:
+			section = strings.TrimSpace(l)
+			continue
+		}
+
+		switch section {
+		case textMarker:
+			body = append(body, l)
+		case attachmentsMarker:
+			attachments = append(attachments, l)
+		default:
+			l = strings.TrimSpace(l)
+			if len(l) == 0 || strings.HasPrefix(l, "#") {
+				continue
+			}
+			seps := strings.SplitN(l, "=", 2)
+			if len(seps) != 2 || strings.TrimSpace(seps[0]) != "thread_ts" {
+				return fmt.Errorf("invalid line %q, only thread_ts can be set above the text", l)
+			}
+			d.ThreadTs = strings.TrimSpace(seps[1])
+		}
+	}
+
+	d.Text = strings.TrimSpace(strings.Join(body, "\n"))
+	d.Attachments = strings.TrimSpace(strings.Join(attachments, "\n"))
+	if len(d.Attachments) > 0 {
+		var a []slack.Attachment
+		if err := json.Unmarshal([]byte(d.Attachments), &a); err != nil {
+			return fmt.Errorf("invalid attachments: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+var (
+	// <@U024BE7LH>, <#C024BE91L|general>, <!here>
+	escapedMention = regexp.MustCompile(`<([@#!])([^>|]+)(\|[^>]*)?>`)
+	// @name, linked by link_names=1, but not the @ of an email address
+	plainMention = regexp.MustCompile(`\B@([a-zA-Z0-9._-]+)`)
+)
+
+// userNames maps user ids to names.
+func (s *Slack) userNames(ctx context.Context) (map[string]string, error) {
+	users, err := s.s.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.Id] = u.Name
+	}
+	return names, nil
+}
+
+// renderMentions shows a message like slack does, with user and channel ids replaced by names.
+func (s *Slack) renderMentions(ctx context.Context, text string, names map[string]string) string {
+	return escapedMention.ReplaceAllStringFunc(text, func(m string) string {
+		sub := escapedMention.FindStringSubmatch(m)
+		label := strings.TrimPrefix(sub[3], "|")
+		switch sub[1] {
+		case "@":
+			if name, ok := names[sub[2]]; ok {
+				return "@" + name
+			}
+		case "#":
+			if len(label) == 0 {
+				return strings.SplitN(s.channelName(ctx, sub[2]), " ", 2)[0]
+			}
+			return "#" + label
+		case "!":
+			if len(label) > 0 {
+				return label
+			}
+			return "@" + sub[2]
+		}
+		return m
+	})
+}
+
+// unknownMentions returns the @names in text which are no users.
+func unknownMentions(text string, names map[string]string) []string {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	var unknown []string
+	for _, m := range plainMention.FindAllStringSubmatch(escapedMention.ReplaceAllString(text, " "), -1) {
+		switch m[1] {
+		case "here", "channel", "everyone":
+			continue
+		}
+		if !known[m[1]] {
+			unknown = append(unknown, m[0])
+		}
+	}
+	return unknown
+}
+
+func (s *Slack) preview(ctx context.Context, to string, d *draft) {
+	fmt.Printf("to %s", to)
+	if len(d.ThreadTs) > 0 {
+		fmt.Printf(", in thread %s", d.ThreadTs)
+	}
+	fmt.Printf("\n----\n")
+
+	names, err := s.userNames(ctx)
+	fmt.Println(s.renderMentions(ctx, d.Text, names))
+
+	if len(d.Attachments) > 0 {
+		var a []slack.Attachment
+		json.Unmarshal([]byte(d.Attachments), &a)
+		for _, attachment := range a {
+			fmt.Printf("| %s\n", strings.Replace(attachment.Text, "\n", "\n| ", -1))
+		}
+	}
+	fmt.Printf("----\n")
+
+	if err != nil {
+		return
+	}
+	for _, name := range unknownMentions(d.Text, names) {
+		fmt.Printf("warning: %s is not a user\n", name)
+	}
+}
+
+// compose writes a message in $EDITOR, shows a preview and posts it after
+// confirmation, an abandoned message is kept for drafts.list.
+func (s *Slack) compose(args []string) {
+	if s.readOnly {
+		fmt.Printf("err: chat.compose changes the workspace, not allowed in read-only mode\n")
+		return
+	}
+
+	ctx := context.Background()
+	params := extractParams(args)
+	d := &draft{Channel: params["channel"], ThreadTs: params["thread_ts"]}
+	if id, ok := params["draft"]; ok {
+		n, _ := strconv.Atoi(id)
+		var err error
+		if d, err = s.drafts.get(n); err != nil {
+			fmt.Printf("err: %s\n", err.Error())
+			return
+		}
+	}
+
+	if len(d.Channel) == 0 {
+		fmt.Printf("err: channel is required\n")
+		return
+	}
+
+	channel, err := s.resolveChannel(ctx, d.Channel)
+	if err != nil {
+		fmt.Printf("err: %s\n", err.Error())
+		return
+	}
+	d.Channel = channel
+	to := s.channelName(ctx, channel)
+
+	text, err := editInEditor(composeTemplate(to, d), ".txt")
+	if err != nil {
+		fmt.Printf("err: %s\n", err.Error())
+		return
+	}
+
+	if err = parseCompose(text, d); err != nil {
+		fmt.Printf("err: %s\n", err.Error())
+		s.saveDraft(d)
+		return
+	}
+	if d.isEmpty() {
+		fmt.Printf("canceled\n")
+		return
+	}
+
+	s.preview(ctx, to, d)
+	if !askYesNo("send? [y/N] ") {
+		fmt.Printf("canceled\n")
+		s.saveDraft(d)
+		return
+	}
+
+	// the draft is sent as it is, quotes and all
+	post := map[string]string{"channel": d.Channel, "text": d.Text, "link_names": "1"}
+	if len(d.ThreadTs) > 0 {
+		post["thread_ts"] = d.ThreadTs
+	}
+	if len(d.Attachments) > 0 {
+		post["attachments"] = d.Attachments
+	}
+	dryRun := s.isDryRun(params)
+	if dryRun {
+		post["dry_run"] = "1"
+	}
+
+	v, err := s.runParams("chat.postMessage", post)
+	printResult(v, err)
+	if err != nil {
+		s.saveDraft(d)
+	} else if d.ID > 0 && !dryRun {
+		s.drafts.remove(d.ID)
+	}
+}
+
+func (s *Slack) saveDraft(d *draft) {
+	if d.isEmpty() {
+		return
+	}
+	if err := s.drafts.save(d); err != nil {
+		fmt.Printf("save draft: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("saved as draft %d, continue with chat.compose draft=%d\n", d.ID, d.ID)
+}
+
+func (s *Slack) handleDrafts(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "list":
+		drafts, err := s.drafts.list()
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"drafts": drafts,
+		}
+	case "delete":
+		err = s.drafts.remove(getIntParam(params, "id", 0))
+	default:
+		return nil, fmt.Errorf("invalid drafts action %s", action)
+	}
+
+	return v, err
+}
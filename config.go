@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// config holds the settings of config.json in the data directory, which
+// apply to every session of the account, whatever flags it starts with.
+type config struct {
+	// ReadOnly refuses commands that change the workspace, like -read-only.
+	ReadOnly bool `json:"read_only"`
+}
+
+func loadConfig(path string) (*config, error) {
+	c := new(config)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("load config %s: %s", path, err.Error())
+	}
+	return c, nil
+}
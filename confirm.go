@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+// destructive commands ask for confirmation before running
+var destructiveCommands = map[string]bool{
+	"channels.archive":      true,
+	"channels.kick":         true,
+	"chat.delete":           true,
+	"conversations.archive": true,
+	"conversations.kick":    true,
+	"files.delete":          true,
+	"groups.archive":        true,
+	"groups.kick":           true,
+	"usergroups.rotate":     true,
+}
+
+func isDestructive(cmd string) bool {
+	return destructiveCommands[strings.ToLower(cmd)]
+}
+
+// confirm shows what a destructive command will do and asks the user to go on.
+func (s *Slack) confirm(cmd string, args []string) bool {
+	// read-only mode refuses it anyway
+	if !s.confirmDestructive || !isDestructive(cmd) || s.readOnly {
+		return true
+	}
+
+	params := extractParams(args)
+	if s.isDryRun(params) {
+		return true
+	}
+
+	fmt.Println(s.describe(context.Background(), cmd, params))
+	return askYesNo("continue? [y/N] ")
+}
+
+func askYesNo(prompt string) bool {
+	answer, err := line(prompt)
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// describe tells what a destructive command will act on, with the #names and
+// @names resolved the way the command will resolve them.
+func (s *Slack) describe(ctx context.Context, cmd string, params map[string]string) string {
+	if resolved, err := s.resolveParams(ctx, params); err == nil {
+		params = resolved
+	}
+
+	switch strings.ToLower(cmd) {
+	case "channels.archive", "groups.archive", "conversations.archive":
+		return fmt.Sprintf("archive %s", s.channelName(ctx, params["channel"]))
+	case "channels.kick", "groups.kick", "conversations.kick":
+		return fmt.Sprintf("kick %s from %s", s.userName(ctx, params["user"]), s.channelName(ctx, params["channel"]))
+	case "chat.delete":
+		desc := fmt.Sprintf("delete message %s in %s", params["ts"], s.channelName(ctx, params["channel"]))
+		if msg := s.messagePreview(ctx, params["channel"], params["ts"]); len(msg) > 0 {
+			desc += "\n\t" + msg
+		}
+		return desc
+	case "usergroups.rotate":
+		id, old, users, err := s.rotation(ctx, params)
+		if err != nil {
+			return fmt.Sprintf("replace the users of user group %s", params["handle"])
+		}
+		names, _ := s.userNames(ctx)
+		diff := diffMembers(old, users).named(names).String()
+		return fmt.Sprintf("replace the users of user group %s\n\t%s", id, strings.Replace(diff, "\n", "\n\t", -1))
+	case "files.delete":
+		file, _, _, err := s.s.GetFileInfo(ctx, params["file"], 1, 1)
+		if err != nil || file == nil {
+			return fmt.Sprintf("delete file %s", params["file"])
+		}
+		return fmt.Sprintf("delete file %s %q", file.Id, file.Title)
+	}
+	return cmd
+}
+
+// channelName returns #name (id) for a channel or group id, or the id if it can't be found.
+func (s *Slack) channelName(ctx context.Context, id string) string {
+	switch {
+	case strings.HasPrefix(id, "C"):
+		ch, err := s.s.GetChannelInfo(ctx, id)
+		if err == nil && ch != nil {
+			return fmt.Sprintf("#%s (%s)", ch.Name, id)
+		}
+	case strings.HasPrefix(id, "G"):
+		g, err := s.getGroup(ctx, id)
+		if err == nil {
+			return fmt.Sprintf("#%s (%s)", g.Name, id)
+		}
+	}
+	return id
+}
+
+// userName returns @name (id) for a user id, or the id if it can't be found.
+func (s *Slack) userName(ctx context.Context, id string) string {
+	u, err := s.s.GetUserInfo(ctx, id)
+	if err != nil || u == nil {
+		return id
+	}
+	return fmt.Sprintf("@%s (%s)", u.Name, id)
+}
+
+// shiftTimestamp moves a slack timestamp by delta microseconds.
+func shiftTimestamp(ts string, delta int64) string {
+	seps := strings.SplitN(ts, ".", 2)
+	sec, err := strconv.ParseInt(seps[0], 10, 64)
+	if err != nil {
+		return ts
+	}
+
+	var usec int64
+	if len(seps) == 2 {
+		if usec, err = strconv.ParseInt(seps[1], 10, 64); err != nil {
+			return ts
+		}
+	}
+
+	usec = sec*1000000 + usec + delta
+	return fmt.Sprintf("%d.%06d", usec/1000000, usec%1000000)
+}
+
+// getMessage fetches the message at ts from the history of a channel, group or
+// IM, or from its thread if it is a reply.
+func (s *Slack) getMessage(ctx context.Context, channel string, ts string) (*slack.Message, error) {
+	historyParam := slack.HistoryParameters{}
+	historyParam.Latest = shiftTimestamp(ts, 1)
+	historyParam.Oldest = shiftTimestamp(ts, -1)
+	historyParam.Count = 1
+
+	var h *History
+	var err error
+	switch {
+	case strings.HasPrefix(channel, "G"):
+		h, err = s.s.GetGroupHistory(ctx, channel, historyParam)
+	case strings.HasPrefix(channel, "D"):
+		h, err = s.s.GetIMHistory(ctx, channel, historyParam)
+	default:
+		h, err = s.s.GetChannelHistory(ctx, channel, historyParam)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range h.Messages {
+		if h.Messages[i].Timestamp == ts {
+			return &h.Messages[i].Message, nil
+		}
+	}
+
+	// thread replies are not in the history
+	replies, err := s.s.GetThreadReplies(ctx, channel, ts)
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in %s", ts, channel)
+	}
+	for i := range replies {
+		if replies[i].Timestamp == ts {
+			return &replies[i].Message, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found in %s", ts, channel)
+}
+
+func (s *Slack) messagePreview(ctx context.Context, channel string, ts string) string {
+	msg, err := s.getMessage(ctx, channel, ts)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s: %s", s.userName(ctx, msg.User), truncate(msg.Text, 80))
+}
+
+// truncate shortens text to n runes in one line.
+func truncate(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		text = string(r[:n]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+// Conversation is a public or private channel, IM or multi-person IM.
+type Conversation struct {
+	Id         string        `json:"id"`
+	Name       string        `json:"name,omitempty"`
+	Created    int64         `json:"created"`
+	Creator    string        `json:"creator,omitempty"`
+	User       string        `json:"user,omitempty"`
+	IsChannel  bool          `json:"is_channel,omitempty"`
+	IsGroup    bool          `json:"is_group,omitempty"`
+	IsIM       bool          `json:"is_im,omitempty"`
+	IsMpIM     bool          `json:"is_mpim,omitempty"`
+	IsPrivate  bool          `json:"is_private,omitempty"`
+	IsArchived bool          `json:"is_archived,omitempty"`
+	IsMember   bool          `json:"is_member,omitempty"`
+	NumMembers int           `json:"num_members,omitempty"`
+	Topic      slack.Topic   `json:"topic"`
+	Purpose    slack.Purpose `json:"purpose"`
+}
+
+type cursorMetadata struct {
+	NextCursor string `json:"next_cursor"`
+}
+
+// pageValues adds the cursor and limit of a paginated call.
+func pageValues(values url.Values, cursor string, limit int) url.Values {
+	if len(cursor) > 0 {
+		values.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	return values
+}
+
+// conversation calls a method which returns the changed conversation.
+func (c *apiClient) conversation(ctx context.Context, method string, values url.Values) (*Conversation, error) {
+	var r struct {
+		Channel *Conversation `json:"channel"`
+	}
+	if err := c.call(ctx, method, values, &r); err != nil {
+		return nil, err
+	}
+	return r.Channel, nil
+}
+
+func (c *apiClient) ArchiveConversation(ctx context.Context, channel string) error {
+	return c.call(ctx, "conversations.archive", url.Values{"channel": {channel}}, nil)
+}
+
+func (c *apiClient) CloseConversation(ctx context.Context, channel string) error {
+	return c.call(ctx, "conversations.close", url.Values{"channel": {channel}}, nil)
+}
+
+func (c *apiClient) CreateConversation(ctx context.Context, name string, private bool) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.create", url.Values{
+		"name":       {name},
+		"is_private": {strconv.FormatBool(private)},
+	})
+}
+
+// GetConversationHistory returns a page of messages, and the cursor of the next page.
+func (c *apiClient) GetConversationHistory(ctx context.Context, channel string, latest string, oldest string, cursor string, limit int) (*History, string, error) {
+	values := url.Values{"channel": {channel}}
+	if len(latest) > 0 {
+		values.Set("latest", latest)
+	}
+	if len(oldest) > 0 {
+		values.Set("oldest", oldest)
+	}
+
+	var r struct {
+		History
+		Metadata cursorMetadata `json:"response_metadata"`
+	}
+	if err := c.call(ctx, "conversations.history", pageValues(values, cursor, limit), &r); err != nil {
+		return nil, "", err
+	}
+	return &r.History, r.Metadata.NextCursor, nil
+}
+
+func (c *apiClient) GetConversationInfo(ctx context.Context, channel string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.info", url.Values{"channel": {channel}})
+}
+
+// GetConversationMembers returns a page of member ids, and the cursor of the next page.
+func (c *apiClient) GetConversationMembers(ctx context.Context, channel string, cursor string, limit int) ([]string, string, error) {
+	var r struct {
+		Members  []string       `json:"members"`
+		Metadata cursorMetadata `json:"response_metadata"`
+	}
+	if err := c.call(ctx, "conversations.members", pageValues(url.Values{"channel": {channel}}, cursor, limit), &r); err != nil {
+		return nil, "", err
+	}
+	return r.Members, r.Metadata.NextCursor, nil
+}
+
+func (c *apiClient) InviteUsersToConversation(ctx context.Context, channel string, users []string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.invite", url.Values{
+		"channel": {channel},
+		"users":   {strings.Join(users, ",")},
+	})
+}
+
+func (c *apiClient) JoinConversation(ctx context.Context, channel string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.join", url.Values{"channel": {channel}})
+}
+
+func (c *apiClient) KickUserFromConversation(ctx context.Context, channel string, user string) error {
+	return c.call(ctx, "conversations.kick", url.Values{"channel": {channel}, "user": {user}}, nil)
+}
+
+func (c *apiClient) LeaveConversation(ctx context.Context, channel string) error {
+	return c.call(ctx, "conversations.leave", url.Values{"channel": {channel}}, nil)
+}
+
+// ListConversations returns a page of conversations of types, like "public_channel,im",
+// and the cursor of the next page.
+func (c *apiClient) ListConversations(ctx context.Context, types string, excludeArchived bool, cursor string, limit int) ([]Conversation, string, error) {
+	values := url.Values{
+		"types":            {types},
+		"exclude_archived": {strconv.FormatBool(excludeArchived)},
+	}
+
+	var r struct {
+		Channels []Conversation `json:"channels"`
+		Metadata cursorMetadata `json:"response_metadata"`
+	}
+	if err := c.call(ctx, "conversations.list", pageValues(values, cursor, limit), &r); err != nil {
+		return nil, "", err
+	}
+	return r.Channels, r.Metadata.NextCursor, nil
+}
+
+func (c *apiClient) MarkConversation(ctx context.Context, channel string, ts string) error {
+	return c.call(ctx, "conversations.mark", url.Values{"channel": {channel}, "ts": {ts}}, nil)
+}
+
+// OpenConversation opens an IM with one user, or a multi-person IM with several.
+func (c *apiClient) OpenConversation(ctx context.Context, users []string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.open", url.Values{
+		"users":     {strings.Join(users, ",")},
+		"return_im": {"true"},
+	})
+}
+
+func (c *apiClient) RenameConversation(ctx context.Context, channel string, name string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.rename", url.Values{"channel": {channel}, "name": {name}})
+}
+
+func (c *apiClient) SetConversationPurpose(ctx context.Context, channel string, purpose string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.setPurpose", url.Values{"channel": {channel}, "purpose": {purpose}})
+}
+
+func (c *apiClient) SetConversationTopic(ctx context.Context, channel string, topic string) (*Conversation, error) {
+	return c.conversation(ctx, "conversations.setTopic", url.Values{"channel": {channel}, "topic": {topic}})
+}
+
+func (c *apiClient) UnarchiveConversation(ctx context.Context, channel string) error {
+	return c.call(ctx, "conversations.unarchive", url.Values{"channel": {channel}}, nil)
+}
+
+// conversationTypes maps the short types of conversations.list to the API ones.
+var conversationTypes = map[string]string{
+	"public":          "public_channel",
+	"public_channel":  "public_channel",
+	"private":         "private_channel",
+	"private_channel": "private_channel",
+	"im":              "im",
+	"mpim":            "mpim",
+}
+
+func parseConversationTypes(types string) (string, error) {
+	var apiTypes []string
+	for _, tp := range strings.Split(types, ",") {
+		tp = strings.TrimSpace(tp)
+		apiType, ok := conversationTypes[tp]
+		if !ok {
+			return "", fmt.Errorf("invalid conversation type %s, must be public, private, im or mpim", tp)
+		}
+		apiTypes = append(apiTypes, apiType)
+	}
+	return strings.Join(apiTypes, ","), nil
+}
+
+// walkPages fetches the page at the cursor param, and with all=true every
+// following page too, page returns the cursor of the next one.
+func walkPages(params map[string]string, page func(cursor string) (string, error)) (string, error) {
+	cursor := params["cursor"]
+	for {
+		next, err := page(cursor)
+		if err != nil || len(next) == 0 || !getBoolParam(params, "all", false) {
+			return next, err
+		}
+		cursor = next
+	}
+}
+
+// resolveUsers maps a comma separated list of @names or ids to ids.
+func (s *Slack) resolveUsers(ctx context.Context, users string) ([]string, error) {
+	var ids []string
+	for _, user := range strings.Split(users, ",") {
+		id, err := s.resolveUser(ctx, strings.TrimSpace(user))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *Slack) handleConversations(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	if ch, ok := params["channel"]; ok {
+		if params["channel"], err = s.resolveChannel(ctx, ch); err != nil {
+			return nil, err
+		}
+	}
+	channel := params["channel"]
+	limit := getIntParam(params, "limit", 100)
+
+	switch action {
+	case "archive":
+		err = s.s.ArchiveConversation(ctx, channel)
+	case "close":
+		err = s.s.CloseConversation(ctx, channel)
+	case "create":
+		ch, err := s.s.CreateConversation(ctx, params["name"], getBoolParam(params, "is_private", false))
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "history":
+		h := &History{}
+		next, err := walkPages(params, func(cursor string) (string, error) {
+			page, next, err := s.s.GetConversationHistory(ctx, channel, params["latest"], params["oldest"], cursor, limit)
+			if err != nil {
+				return "", err
+			}
+			h.Messages = append(h.Messages, page.Messages...)
+			h.HasMore = page.HasMore
+			return next, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, h.Messages)
+		markThreads(channel, h.Messages)
+		v = map[string]interface{}{
+			"messages":    h.Messages,
+			"has_more":    h.HasMore,
+			"next_cursor": next,
+		}
+	case "info":
+		ch, err := s.s.GetConversationInfo(ctx, channel)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "invite":
+		users, err := s.resolveUsers(ctx, params["users"])
+		if err != nil {
+			return nil, err
+		}
+		ch, err := s.s.InviteUsersToConversation(ctx, channel, users)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "join":
+		ch, err := s.s.JoinConversation(ctx, channel)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "kick":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		err = s.s.KickUserFromConversation(ctx, channel, user)
+		if err != nil {
+			return nil, err
+		}
+	case "leave":
+		err = s.s.LeaveConversation(ctx, channel)
+	case "list":
+		types, err := parseConversationTypes(getStringParam(params, "types", "public"))
+		if err != nil {
+			return nil, err
+		}
+
+		var chs []Conversation
+		next, err := walkPages(params, func(cursor string) (string, error) {
+			page, next, err := s.s.ListConversations(ctx, types, getBoolParam(params, "exclude_archived", false), cursor, limit)
+			chs = append(chs, page...)
+			return next, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channels":    chs,
+			"next_cursor": next,
+		}
+	case "mark":
+		err = s.s.MarkConversation(ctx, channel, params["ts"])
+	case "members":
+		var members []string
+		next, err := walkPages(params, func(cursor string) (string, error) {
+			page, next, err := s.s.GetConversationMembers(ctx, channel, cursor, limit)
+			members = append(members, page...)
+			return next, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"members":     members,
+			"next_cursor": next,
+		}
+	case "open":
+		users, err := s.resolveUsers(ctx, params["users"])
+		if err != nil {
+			return nil, err
+		}
+		ch, err := s.s.OpenConversation(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "rename":
+		ch, err := s.s.RenameConversation(ctx, channel, params["name"])
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "setpurpose":
+		ch, err := s.s.SetConversationPurpose(ctx, channel, params["purpose"])
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "settopic":
+		ch, err := s.s.SetConversationTopic(ctx, channel, params["topic"])
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"channel": ch,
+		}
+	case "unarchive":
+		err = s.s.UnarchiveConversation(ctx, channel)
+	default:
+		return nil, fmt.Errorf("invalid conversations action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+type DNDStatus struct {
+	DNDEnabled      bool  `json:"dnd_enabled"`
+	NextDNDStartTs  int64 `json:"next_dnd_start_ts"`
+	NextDNDEndTs    int64 `json:"next_dnd_end_ts"`
+	SnoozeEnabled   bool  `json:"snooze_enabled"`
+	SnoozeEndtime   int64 `json:"snooze_endtime,omitempty"`
+	SnoozeRemaining int   `json:"snooze_remaining,omitempty"`
+
+	// Active is a hint added by the dnd and presence commands, not part of the API.
+	Active bool `json:"active"`
+}
+
+// isActive reports whether notifications are paused at now, by a snooze or
+// by the scheduled DND hours.
+func (d *DNDStatus) isActive(now time.Time) bool {
+	if d.SnoozeEnabled && (d.SnoozeEndtime == 0 || now.Unix() < d.SnoozeEndtime) {
+		return true
+	}
+	return d.DNDEnabled && d.NextDNDStartTs <= now.Unix() && now.Unix() < d.NextDNDEndTs
+}
+
+func (c *apiClient) dnd(ctx context.Context, method string, values url.Values) (*DNDStatus, error) {
+	d := new(DNDStatus)
+	if err := c.call(ctx, method, values, d); err != nil {
+		return nil, err
+	}
+	d.Active = d.isActive(time.Now())
+	return d, nil
+}
+
+func (c *apiClient) EndDND(ctx context.Context) error {
+	return c.call(ctx, "dnd.endDnd", url.Values{}, nil)
+}
+
+func (c *apiClient) EndSnooze(ctx context.Context) (*DNDStatus, error) {
+	return c.dnd(ctx, "dnd.endSnooze", url.Values{})
+}
+
+// GetDNDInfo returns the DND status of user, or of the token user if empty.
+func (c *apiClient) GetDNDInfo(ctx context.Context, user string) (*DNDStatus, error) {
+	values := url.Values{}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+	return c.dnd(ctx, "dnd.info", values)
+}
+
+// GetDNDTeamInfo returns the DND status of users by id, it has no snooze state.
+func (c *apiClient) GetDNDTeamInfo(ctx context.Context, users []string) (map[string]*DNDStatus, error) {
+	values := url.Values{}
+	if len(users) > 0 {
+		values.Set("users", strings.Join(users, ","))
+	}
+
+	var r struct {
+		Users map[string]*DNDStatus `json:"users"`
+	}
+	if err := c.call(ctx, "dnd.teamInfo", values, &r); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, d := range r.Users {
+		d.Active = d.isActive(now)
+	}
+	return r.Users, nil
+}
+
+func (c *apiClient) SetSnooze(ctx context.Context, minutes int) (*DNDStatus, error) {
+	return c.dnd(ctx, "dnd.setSnooze", url.Values{"num_minutes": {strconv.Itoa(minutes)}})
+}
+
+// presenceDND is the presence of a user with the DND status, which decides
+// whether a notification reaches them.
+type presenceDND struct {
+	*slack.UserPresence
+
+	DND      *DNDStatus `json:"dnd,omitempty"`
+	DNDError string     `json:"dnd_error,omitempty"`
+}
+
+func (s *Slack) handleDND(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "enddnd":
+		err = s.s.EndDND(ctx)
+	case "endsnooze":
+		v, err = s.s.EndSnooze(ctx)
+	case "info":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		v, err = s.s.GetDNDInfo(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	case "setsnooze":
+		minutes := getIntParam(params, "num_minutes", getIntParam(params, "minutes", 0))
+		if minutes <= 0 {
+			return nil, fmt.Errorf("minutes must be a positive number")
+		}
+		v, err = s.s.SetSnooze(ctx, minutes)
+	case "teaminfo":
+		var users []string
+		if len(params["users"]) > 0 {
+			if users, err = s.resolveUsers(ctx, params["users"]); err != nil {
+				return nil, err
+			}
+		}
+		dnd, err := s.s.GetDNDTeamInfo(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"users": dnd,
+		}
+	default:
+		return nil, fmt.Errorf("invalid dnd action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+type dryRunKey struct{}
+
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRunContext(ctx context.Context) bool {
+	on, _ := ctx.Value(dryRunKey{}).(bool)
+	return on
+}
+
+func (s *Slack) isDryRun(params map[string]string) bool {
+	return s.dryRun || getIntParam(params, "dry_run", 0) == 1
+}
+
+// dryRunTransport prints every request of a dry run command as a curl command.
+// Requests which change the workspace are answered with an empty ok response
+// instead of being sent, reads are sent, so names resolve and the printed
+// requests have the ids they would have.
+type dryRunTransport struct {
+	base http.RoundTripper
+}
+
+func newDryRunTransport(base http.RoundTripper) *dryRunTransport {
+	return &dryRunTransport{base: base}
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isDryRunContext(req.Context()) {
+		return t.base.RoundTrip(req)
+	}
+
+	cmd, err := curlCommand(req)
+	if err != nil {
+		return nil, err
+	}
+	if isIdempotent(path.Base(req.URL.Path)) {
+		fmt.Printf("%s # a read, sent\n", cmd)
+		return t.base.RoundTrip(req)
+	}
+	fmt.Println(cmd)
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"ok":true}`)),
+		Request:    req,
+	}, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func curlCommand(req *http.Request) (string, error) {
+	p, err := readRequestParams(req)
+	if err != nil {
+		return "", err
+	}
+
+	u := *req.URL
+	u.RawQuery = ""
+	args := []string{"curl", "-X", req.Method, shellQuote(u.String())}
+
+	flag := "--data-urlencode"
+	if p.multipart {
+		flag = "-F"
+	}
+	for _, key := range sortedKeys(p.form) {
+		// hide the token so the printed command can be shared
+		if key == "token" {
+			args = append(args, flag, `"token=$SLACK_TOKEN"`)
+			continue
+		}
+		for _, v := range p.form[key] {
+			args = append(args, flag, shellQuote(key+"="+v))
+		}
+	}
+
+	if len(p.raw) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(p.raw)))
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// requestParams are the parameters of a Web API request,
+// file fields of a multipart upload hold "@filename".
+type requestParams struct {
+	form      url.Values
+	multipart bool
+	// body which is neither a form nor multipart
+	raw []byte
+}
+
+// readRequestParams reads the query and body parameters of req,
+// the body is restored so req can still be sent.
+func readRequestParams(req *http.Request) (*requestParams, error) {
+	p := &requestParams{form: req.URL.Query()}
+	if req.Body == nil {
+		return p, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, mediaParams, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range form {
+			p.form[key] = append(p.form[key], values...)
+		}
+	case "multipart/form-data":
+		p.multipart = true
+		r := multipart.NewReader(bytes.NewReader(body), mediaParams["boundary"])
+		for {
+			part, err := r.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			key := part.FormName()
+			if name := part.FileName(); len(name) > 0 {
+				p.form.Add(key, "@"+name)
+				continue
+			}
+
+			v, err := ioutil.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			p.form.Add(key, string(v))
+		}
+	default:
+		p.raw = body
+	}
+
+	return p, nil
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
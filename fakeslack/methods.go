@@ -0,0 +1,1681 @@
+package fakeslack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var methods = map[string]methodFunc{
+	"auth.test": authTest,
+
+	"channels.archive":    archive(false, true),
+	"channels.create":     create(false),
+	"channels.history":    history(false),
+	"channels.info":       channelsInfo,
+	"channels.invite":     invite(false),
+	"channels.join":       channelsJoin,
+	"channels.kick":       kick(false),
+	"channels.leave":      channelsLeave,
+	"channels.list":       list(false),
+	"channels.mark":       mark(false),
+	"channels.rename":     rename(false),
+	"channels.setPurpose": setPurpose(false),
+	"channels.setTopic":   setTopic(false),
+	"channels.unarchive":  archive(false, false),
+
+	"groups.archive":     archive(true, true),
+	"groups.close":       groupsOpen(false),
+	"groups.create":      create(true),
+	"groups.createChild": groupsCreateChild,
+	"groups.history":     history(true),
+	"groups.invite":      invite(true),
+	"groups.kick":        kick(true),
+	"groups.leave":       groupsLeave,
+	"groups.list":        list(true),
+	"groups.mark":        mark(true),
+	"groups.open":        groupsOpen(true),
+	"groups.rename":      rename(true),
+	"groups.setPurpose":  setPurpose(true),
+	"groups.setTopic":    setTopic(true),
+	"groups.unarchive":   archive(true, false),
+
+	"chat.delete":      chatDelete,
+	"chat.postMessage": chatPostMessage,
+	"chat.update":      chatUpdate,
+
+	"conversations.archive":    conversationsArchive(true),
+	"conversations.close":      conversationsClose,
+	"conversations.create":     conversationsCreate,
+	"conversations.history":    conversationsHistory,
+	"conversations.info":       conversationsInfo,
+	"conversations.invite":     conversationsInvite,
+	"conversations.join":       conversationsJoin,
+	"conversations.kick":       conversationsKick,
+	"conversations.leave":      conversationsLeave,
+	"conversations.list":       conversationsList,
+	"conversations.mark":       conversationsMark,
+	"conversations.members":    conversationsMembers,
+	"conversations.open":       conversationsOpen,
+	"conversations.rename":     conversationsRename,
+	"conversations.replies":    conversationsReplies,
+	"conversations.setPurpose": conversationsSetPurpose,
+	"conversations.setTopic":   conversationsSetTopic,
+	"conversations.unarchive":  conversationsArchive(false),
+
+	"dnd.endDnd":    dndEndDnd,
+	"dnd.endSnooze": dndEndSnooze,
+	"dnd.info":      dndInfo,
+	"dnd.setSnooze": dndSetSnooze,
+	"dnd.teamInfo":  dndTeamInfo,
+
+	"emoji.list": emojiList,
+
+	"files.delete": filesDelete,
+	"files.info":   filesInfo,
+	"files.list":   filesList,
+	"files.upload": filesUpload,
+
+	"im.close":   imOpenClose(false),
+	"im.history": imHistory,
+	"im.list":    imList,
+	"im.mark":    imMark,
+	"im.open":    imOpenClose(true),
+
+	"mpim.close":   mpimOpenClose(false),
+	"mpim.history": mpimHistory,
+	"mpim.list":    mpimList,
+	"mpim.mark":    mpimMark,
+	"mpim.open":    mpimOpenClose(true),
+
+	"pins.add":    pinsAdd,
+	"pins.list":   pinsList,
+	"pins.remove": pinsRemove,
+
+	"reactions.add":    reactionsAdd,
+	"reactions.get":    reactionsGet,
+	"reactions.list":   reactionsList,
+	"reactions.remove": reactionsRemove,
+
+	"reminders.add":      remindersAdd,
+	"reminders.complete": remindersComplete,
+	"reminders.delete":   remindersDelete,
+	"reminders.info":     remindersInfo,
+	"reminders.list":     remindersList,
+
+	"search.all":      search(true, true),
+	"search.files":    search(false, true),
+	"search.messages": search(true, false),
+
+	"stars.add":    starsAdd,
+	"stars.list":   starsList,
+	"stars.remove": starsRemove,
+
+	"team.accessLogs":   teamAccessLogs,
+	"team.billableInfo": teamBillableInfo,
+	"team.info":         teamInfo,
+	"team.profile.get":  teamProfileGet,
+
+	"usergroups.create":       usergroupsCreate,
+	"usergroups.disable":      usergroupsEnable(false),
+	"usergroups.enable":       usergroupsEnable(true),
+	"usergroups.list":         usergroupsList,
+	"usergroups.update":       usergroupsUpdate,
+	"usergroups.users.list":   usergroupsUsersList,
+	"usergroups.users.update": usergroupsUsersUpdate,
+
+	"users.getPresence": usersGetPresence,
+	"users.info":        usersInfo,
+	"users.list":        usersList,
+	"users.profile.get": usersProfileGet,
+	"users.profile.set": usersProfileSet,
+	"users.setActive":   usersSetActive,
+	"users.setPhoto":    usersSetPhoto,
+	"users.setPresence": usersSetPresence,
+}
+
+// teamAccessLogs returns the logins before the before param, newest first.
+func teamAccessLogs(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	before := int64(intValue(form, "before", 0))
+	logins := []*Login{}
+	for i := len(s.logins) - 1; i >= 0; i-- {
+		if before == 0 || s.logins[i].DateLast < before {
+			logins = append(logins, s.logins[i])
+		}
+	}
+
+	start, end, paging := paginate(len(logins), form)
+	return map[string]interface{}{"logins": logins[start:end], "paging": paging}, nil
+}
+
+func teamBillableInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	info := make(map[string]interface{})
+	for id, u := range s.users {
+		if user := form.Get("user"); len(user) > 0 && user != id {
+			continue
+		}
+		info[id] = map[string]bool{"billing_active": !u.Deleted}
+	}
+	return map[string]interface{}{"billable_info": info}, nil
+}
+
+func teamInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"team": map[string]interface{}{
+			"id":           "T00000001",
+			"name":         "fake",
+			"domain":       "fake",
+			"email_domain": "example.com",
+			"icon":         map[string]interface{}{"image_default": true},
+		},
+	}, nil
+}
+
+func teamProfileGet(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{"profile": map[string]interface{}{"fields": s.fields}}, nil
+}
+
+func authTest(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"url":     s.URL + "/",
+		"team":    "fake",
+		"user":    s.me.Name,
+		"team_id": "T00000001",
+		"user_id": s.me.Id,
+	}, nil
+}
+
+func channelKey(group bool) string {
+	if group {
+		return "group"
+	}
+	return "channel"
+}
+
+func archive(group bool, archived bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		if ch.IsArchived == archived {
+			if archived {
+				return nil, apiError("already_archived")
+			}
+			return nil, apiError("not_archived")
+		}
+		ch.IsArchived = archived
+		return nil, nil
+	}
+}
+
+func create(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		name := form.Get("name")
+		if len(name) == 0 {
+			return nil, apiError("no_channel")
+		}
+		if s.channelByName(name) != nil {
+			return nil, apiError("name_taken")
+		}
+
+		ch := s.addChannel(name, group)
+		return map[string]interface{}{channelKey(group): ch}, nil
+	}
+}
+
+func history(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		return s.history(ch.Id, form), nil
+	}
+}
+
+func channelsInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.channel(form, false)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+func isMember(ch *Channel, user string) bool {
+	for _, m := range ch.Members {
+		if m == user {
+			return true
+		}
+	}
+	return false
+}
+
+func removeMember(ch *Channel, user string) {
+	for i, m := range ch.Members {
+		if m == user {
+			ch.Members = append(ch.Members[:i], ch.Members[i+1:]...)
+			return
+		}
+	}
+}
+
+func invite(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		u, err := s.user(form)
+		if err != nil {
+			return nil, err
+		}
+
+		in := isMember(ch, u.Id)
+		if !in {
+			ch.Members = append(ch.Members, u.Id)
+		}
+
+		if group {
+			return map[string]interface{}{"group": ch, "already_in_group": in}, nil
+		}
+		if in {
+			return nil, apiError("already_in_channel")
+		}
+		return map[string]interface{}{"channel": ch}, nil
+	}
+}
+
+func channelsJoin(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch := s.channelByName(strings.TrimPrefix(form.Get("name"), "#"))
+	if ch == nil || ch.IsGroup {
+		return nil, apiError("channel_not_found")
+	}
+	if !isMember(ch, s.me.Id) {
+		ch.Members = append(ch.Members, s.me.Id)
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+func kick(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		u, err := s.user(form)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember(ch, u.Id) {
+			return nil, apiError("not_in_channel")
+		}
+		removeMember(ch, u.Id)
+		return nil, nil
+	}
+}
+
+func channelsLeave(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.channel(form, false)
+	if err != nil {
+		return nil, err
+	}
+	in := isMember(ch, s.me.Id)
+	removeMember(ch, s.me.Id)
+	return map[string]interface{}{"not_in_channel": !in}, nil
+}
+
+func groupsLeave(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.channel(form, true)
+	if err != nil {
+		return nil, err
+	}
+	removeMember(ch, s.me.Id)
+	return nil, nil
+}
+
+func list(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		chs := sortedChannels(s.channels, group, form.Get("exclude_archived") == "1")
+		if group {
+			return map[string]interface{}{"groups": chs}, nil
+		}
+		return map[string]interface{}{"channels": chs}, nil
+	}
+}
+
+func mark(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		_, err := s.channel(form, group)
+		return nil, err
+	}
+}
+
+func rename(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		if other := s.channelByName(form.Get("name")); other != nil && other != ch {
+			return nil, apiError("name_taken")
+		}
+		ch.Name = form.Get("name")
+		// groups.rename answers with channel too
+		return map[string]interface{}{"channel": ch}, nil
+	}
+}
+
+func setPurpose(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		ch.Purpose = Topic{Value: form.Get("purpose"), Creator: s.me.Id, LastSet: s.start}
+		return map[string]interface{}{"purpose": ch.Purpose.Value}, nil
+	}
+}
+
+func setTopic(group bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, group)
+		if err != nil {
+			return nil, err
+		}
+		ch.Topic = Topic{Value: form.Get("topic"), Creator: s.me.Id, LastSet: s.start}
+		return map[string]interface{}{"topic": ch.Topic.Value}, nil
+	}
+}
+
+func groupsOpen(open bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.channel(form, true)
+		if err != nil {
+			return nil, err
+		}
+		noop := ch.IsOpen == open
+		ch.IsOpen = open
+		if open {
+			return map[string]interface{}{"no_op": noop, "already_open": noop}, nil
+		}
+		return map[string]interface{}{"no_op": noop, "already_closed": noop}, nil
+	}
+}
+
+func groupsCreateChild(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.channel(form, true)
+	if err != nil {
+		return nil, err
+	}
+	ch.IsArchived = true
+
+	child := s.addChannel(ch.Name+"-1", true)
+	return map[string]interface{}{"group": child}, nil
+}
+
+// conversation checks a message can be sent to a channel, group or IM.
+func (s *Server) conversation(id string) error {
+	if ch, ok := s.channels[id]; ok {
+		if ch.IsArchived {
+			return apiError("is_archived")
+		}
+		return nil
+	}
+	if _, ok := s.ims[id]; ok {
+		return nil
+	}
+	if _, ok := s.mpims[id]; ok {
+		return nil
+	}
+	return apiError("channel_not_found")
+}
+
+func chatPostMessage(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	if ch := s.channelByName(strings.TrimPrefix(channel, "#")); ch != nil {
+		channel = ch.Id
+	}
+	if err := s.conversation(channel); err != nil {
+		return nil, err
+	}
+	if len(form.Get("text")) == 0 && len(form.Get("attachments")) == 0 {
+		return nil, apiError("no_text")
+	}
+
+	var msg *Message
+	if threadTs := form.Get("thread_ts"); len(threadTs) > 0 {
+		var err error
+		if msg, err = s.addReply(channel, threadTs, s.me.Id, form.Get("text")); err != nil {
+			return nil, err
+		}
+		if form.Get("reply_broadcast") == "true" {
+			msg.SubType = "thread_broadcast"
+		}
+	} else {
+		msg = s.addMessage(channel, s.me.Id, form.Get("text"))
+	}
+	return map[string]interface{}{"channel": channel, "ts": msg.Timestamp, "message": msg}, nil
+}
+
+func chatUpdate(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	_, msg := s.findMessage(channel, form.Get("ts"))
+	if msg == nil {
+		return nil, apiError("message_not_found")
+	}
+	msg.Text = form.Get("text")
+	return map[string]interface{}{"channel": channel, "ts": msg.Timestamp, "text": msg.Text}, nil
+}
+
+func chatDelete(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	i, msg := s.findMessage(channel, form.Get("ts"))
+	if msg == nil {
+		return nil, apiError("message_not_found")
+	}
+	msgs := s.messages[channel]
+	s.messages[channel] = append(msgs[:i], msgs[i+1:]...)
+	return map[string]interface{}{"channel": channel, "ts": msg.Timestamp}, nil
+}
+
+// anyChannel is the public or private channel of a conversations method.
+func (s *Server) anyChannel(form url.Values) (*Channel, error) {
+	ch, ok := s.channels[form.Get("channel")]
+	if !ok {
+		return nil, apiError("channel_not_found")
+	}
+	return ch, nil
+}
+
+func conversationsArchive(archived bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		ch, err := s.anyChannel(form)
+		if err != nil {
+			return nil, err
+		}
+		return archive(ch.IsGroup, archived)(s, form, r)
+	}
+}
+
+// conversationsClose closes an IM or a multi-person IM.
+func conversationsClose(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.mpims[form.Get("channel")]; ok {
+		return mpimOpenClose(false)(s, form, r)
+	}
+	return imOpenClose(false)(s, form, r)
+}
+
+func conversationsCreate(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	private := form.Get("is_private") == "true"
+	v, err := create(private)(s, form, r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": v[channelKey(private)]}, nil
+}
+
+// conversationsHistory pages newest first with the index of the next message as cursor.
+func conversationsHistory(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	if _, ok := s.channels[channel]; !ok {
+		if _, ok = s.ims[channel]; !ok {
+			return nil, apiError("channel_not_found")
+		}
+	}
+
+	all := url.Values{"latest": {form.Get("latest")}, "oldest": {form.Get("oldest")}, "count": {"1000000"}}
+	msgs := s.history(channel, all)["messages"].([]*Message)
+	start, end, next := cursorPage(len(msgs), form)
+	return map[string]interface{}{
+		"messages":          msgs[start:end],
+		"has_more":          len(next) > 0,
+		"response_metadata": map[string]string{"next_cursor": next},
+	}, nil
+}
+
+func conversationsInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if im, ok := s.ims[form.Get("channel")]; ok {
+		return map[string]interface{}{"channel": im}, nil
+	}
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+func conversationsInvite(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, id := range strings.Split(form.Get("users"), ",") {
+		if _, ok := s.users[id]; !ok {
+			return nil, apiError("user_not_found")
+		}
+		if isMember(ch, id) {
+			return nil, apiError("already_in_channel")
+		}
+		ids = append(ids, id)
+	}
+	ch.Members = append(ch.Members, ids...)
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+// conversationsJoin joins a public channel by id, unlike channels.join which takes a name.
+func conversationsJoin(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	if ch.IsGroup {
+		return nil, apiError("method_not_supported_for_channel_type")
+	}
+	if !isMember(ch, s.me.Id) {
+		ch.Members = append(ch.Members, s.me.Id)
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+func conversationsKick(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	return kick(ch.IsGroup)(s, form, r)
+}
+
+func conversationsLeave(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember(ch, s.me.Id) {
+		return map[string]interface{}{"not_in_channel": true}, nil
+	}
+	removeMember(ch, s.me.Id)
+	return nil, nil
+}
+
+func conversationsList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	types := form.Get("types")
+	if len(types) == 0 {
+		types = "public_channel"
+	}
+
+	excludeArchived := form.Get("exclude_archived") == "true"
+	var chs []interface{}
+	for _, tp := range strings.Split(types, ",") {
+		switch tp {
+		case "public_channel", "private_channel":
+			for _, ch := range sortedChannels(s.channels, tp == "private_channel", excludeArchived) {
+				chs = append(chs, ch)
+			}
+		case "im":
+			ids := make([]string, 0, len(s.ims))
+			for id := range s.ims {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				chs = append(chs, s.ims[id])
+			}
+		case "mpim":
+			for _, mpim := range sortedChannels(s.mpims, true, excludeArchived) {
+				chs = append(chs, mpim)
+			}
+		default:
+			return nil, apiError("invalid_types")
+		}
+	}
+
+	start, end, next := cursorPage(len(chs), form)
+	return map[string]interface{}{
+		"channels":          chs[start:end],
+		"response_metadata": map[string]string{"next_cursor": next},
+	}, nil
+}
+
+func conversationsMark(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	if _, ok := s.channels[channel]; ok {
+		return nil, nil
+	}
+	if _, ok := s.ims[channel]; ok {
+		return nil, nil
+	}
+	if _, ok := s.mpims[channel]; ok {
+		return nil, nil
+	}
+	return nil, apiError("channel_not_found")
+}
+
+func conversationsMembers(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	start, end, next := cursorPage(len(ch.Members), form)
+	return map[string]interface{}{
+		"members":           ch.Members[start:end],
+		"response_metadata": map[string]string{"next_cursor": next},
+	}, nil
+}
+
+// conversationsOpen opens an IM with one user, or a multi-person IM with several.
+func conversationsOpen(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	users := strings.Split(form.Get("users"), ",")
+	if len(users) == 1 {
+		v, err := imOpenClose(true)(s, url.Values{"user": users}, r)
+		if err != nil {
+			return nil, err
+		}
+		// the whole IM, not only its id like im.open
+		id := v["channel"].(map[string]string)["id"]
+		return map[string]interface{}{"no_op": v["no_op"], "already_open": v["already_open"], "channel": s.ims[id]}, nil
+	}
+
+	v, err := mpimOpenClose(true)(s, form, r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": v["group"]}, nil
+}
+
+func conversationsRename(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	return rename(ch.IsGroup)(s, form, r)
+}
+
+func conversationsSetPurpose(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = setPurpose(ch.IsGroup)(s, form, r); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+func conversationsSetTopic(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ch, err := s.anyChannel(form)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = setTopic(ch.IsGroup)(s, form, r); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"channel": ch}, nil
+}
+
+// conversationsReplies pages with the index of the next message as cursor.
+func conversationsReplies(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	if err := s.conversation(channel); err != nil {
+		return nil, err
+	}
+
+	ts := form.Get("ts")
+	_, parent := s.findMessage(channel, ts)
+	if parent == nil {
+		return nil, apiError("thread_not_found")
+	}
+	// ts may be any message of the thread
+	if len(parent.ThreadTs) > 0 && parent.ThreadTs != ts {
+		ts = parent.ThreadTs
+		_, parent = s.findMessage(channel, ts)
+	}
+
+	thread := []*Message{parent}
+	for _, msg := range s.messages[channel] {
+		if msg.ThreadTs == ts && msg != parent {
+			thread = append(thread, msg)
+		}
+	}
+
+	start, end, next := cursorPage(len(thread), form)
+	return map[string]interface{}{
+		"messages":          thread[start:end],
+		"has_more":          len(next) > 0,
+		"response_metadata": map[string]string{"next_cursor": next},
+	}, nil
+}
+
+// dndStatus is the dnd.info answer for u, teamInfo leaves the snooze out.
+func dndStatus(u *User, snooze bool) map[string]interface{} {
+	d := map[string]interface{}{
+		"dnd_enabled":       u.DND.Enabled,
+		"next_dnd_start_ts": u.DND.Start.Unix(),
+		"next_dnd_end_ts":   u.DND.End.Unix(),
+	}
+	if !u.DND.Enabled {
+		d["next_dnd_start_ts"], d["next_dnd_end_ts"] = 1, 1
+	}
+	if snooze {
+		remaining := int(time.Until(u.DND.SnoozeEnd).Seconds())
+		d["snooze_enabled"] = remaining > 0
+		if remaining > 0 {
+			d["snooze_endtime"] = u.DND.SnoozeEnd.Unix()
+			d["snooze_remaining"] = remaining
+		}
+	}
+	return d
+}
+
+func dndEndDnd(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	s.me.DND.End = time.Now()
+	s.me.DND.SnoozeEnd = time.Time{}
+	return nil, nil
+}
+
+func dndEndSnooze(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if time.Now().After(s.me.DND.SnoozeEnd) {
+		return nil, apiError("snooze_not_active")
+	}
+	s.me.DND.SnoozeEnd = time.Time{}
+	return dndStatus(s.me, true), nil
+}
+
+func dndInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	u, err := s.profileUser(form)
+	if err != nil {
+		return nil, err
+	}
+	return dndStatus(u, true), nil
+}
+
+func dndSetSnooze(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	minutes := intValue(form, "num_minutes", 0)
+	if minutes <= 0 {
+		return nil, apiError("invalid_minutes")
+	}
+	s.me.DND.SnoozeEnd = time.Now().Add(time.Duration(minutes) * time.Minute)
+	return dndStatus(s.me, true), nil
+}
+
+func dndTeamInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	users := make(map[string]interface{})
+	for _, id := range strings.Split(form.Get("users"), ",") {
+		if len(id) == 0 {
+			id = s.me.Id
+		}
+		u, ok := s.users[id]
+		if !ok {
+			return nil, apiError("user_not_found")
+		}
+		users[id] = dndStatus(u, false)
+	}
+	return map[string]interface{}{"users": users}, nil
+}
+
+func emojiList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{"emoji": s.emoji}, nil
+}
+
+func filesDelete(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.files[form.Get("file")]; !ok {
+		return nil, apiError("file_not_found")
+	}
+	delete(s.files, form.Get("file"))
+	return nil, nil
+}
+
+func filesInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	f, ok := s.files[form.Get("file")]
+	if !ok {
+		return nil, apiError("file_not_found")
+	}
+	_, _, paging := paginate(0, form)
+	return map[string]interface{}{"file": f, "comments": []interface{}{}, "paging": paging}, nil
+}
+
+func filesList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	files := []*File{}
+	for _, f := range s.files {
+		if user := form.Get("user"); len(user) > 0 && f.User != user {
+			continue
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Id < files[j].Id
+	})
+
+	start, end, paging := paginate(len(files), form)
+	return map[string]interface{}{"files": files[start:end], "paging": paging}, nil
+}
+
+func filesUpload(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	f := &File{
+		Id:       s.newID("F"),
+		Created:  s.start,
+		Name:     form.Get("filename"),
+		Title:    form.Get("title"),
+		Filetype: form.Get("filetype"),
+		User:     s.me.Id,
+		Content:  form.Get("content"),
+		Channels: []string{},
+	}
+
+	if r.MultipartForm != nil {
+		if fhs := r.MultipartForm.File["file"]; len(fhs) > 0 {
+			rd, err := fhs[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := ioutil.ReadAll(rd)
+			rd.Close()
+			if err != nil {
+				return nil, err
+			}
+			f.Content = string(data)
+			if len(f.Name) == 0 {
+				f.Name = fhs[0].Filename
+			}
+		}
+	}
+	if len(f.Title) == 0 {
+		f.Title = f.Name
+	}
+	f.Size = len(f.Content)
+
+	for _, ch := range strings.Split(form.Get("channels"), ",") {
+		if len(ch) > 0 {
+			f.Channels = append(f.Channels, ch)
+		}
+	}
+
+	s.files[f.Id] = f
+	return map[string]interface{}{"file": f}, nil
+}
+
+func (s *Server) imByUser(user string) *IM {
+	for _, im := range s.ims {
+		if im.User == user {
+			return im
+		}
+	}
+	return nil
+}
+
+func imOpenClose(open bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		if !open {
+			im, ok := s.ims[form.Get("channel")]
+			if !ok {
+				return nil, apiError("channel_not_found")
+			}
+			noop := !im.IsOpen
+			im.IsOpen = false
+			return map[string]interface{}{"no_op": noop, "already_closed": noop}, nil
+		}
+
+		u, err := s.user(form)
+		if err != nil {
+			return nil, err
+		}
+
+		im := s.imByUser(u.Id)
+		noop := im != nil && im.IsOpen
+		if im == nil {
+			im = &IM{Id: s.newID("D"), IsIM: true, User: u.Id, Created: s.start}
+			s.ims[im.Id] = im
+		}
+		im.IsOpen = true
+		return map[string]interface{}{"no_op": noop, "already_open": noop, "channel": map[string]string{"id": im.Id}}, nil
+	}
+}
+
+func imHistory(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.ims[form.Get("channel")]; !ok {
+		return nil, apiError("channel_not_found")
+	}
+	return s.history(form.Get("channel"), form), nil
+}
+
+func imList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	ims := []*IM{}
+	for _, im := range s.ims {
+		ims = append(ims, im)
+	}
+	sort.Slice(ims, func(i, j int) bool {
+		return ims[i].Id < ims[j].Id
+	})
+	return map[string]interface{}{"ims": ims}, nil
+}
+
+func imMark(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.ims[form.Get("channel")]; !ok {
+		return nil, apiError("channel_not_found")
+	}
+	return nil, nil
+}
+
+// mpimByMembers finds the multi-person IM of exactly members.
+func (s *Server) mpimByMembers(members []string) *Channel {
+	for _, mpim := range s.mpims {
+		if len(mpim.Members) != len(members) {
+			continue
+		}
+		same := true
+		for _, m := range members {
+			same = same && isMember(mpim, m)
+		}
+		if same {
+			return mpim
+		}
+	}
+	return nil
+}
+
+func mpimOpenClose(open bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		if !open {
+			mpim, ok := s.mpims[form.Get("channel")]
+			if !ok {
+				return nil, apiError("channel_not_found")
+			}
+			mpim.IsOpen = false
+			return nil, nil
+		}
+
+		members := []string{s.me.Id}
+		names := []string{s.me.Name}
+		for _, id := range strings.Split(form.Get("users"), ",") {
+			u, ok := s.users[id]
+			if !ok {
+				return nil, apiError("user_not_found")
+			}
+			if u.Id != s.me.Id {
+				members = append(members, u.Id)
+				names = append(names, u.Name)
+			}
+		}
+		if len(members) < 3 {
+			return nil, apiError("not_enough_users")
+		}
+
+		mpim := s.mpimByMembers(members)
+		if mpim == nil {
+			mpim = &Channel{
+				Id:      s.newID("G"),
+				Name:    "mpdm-" + strings.Join(names, "--") + "-1",
+				Created: s.start,
+				Creator: s.me.Id,
+				IsGroup: true,
+				IsMpIM:  true,
+				Members: members,
+			}
+			s.mpims[mpim.Id] = mpim
+		}
+		mpim.IsOpen = true
+		return map[string]interface{}{"group": mpim}, nil
+	}
+}
+
+func mpimHistory(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.mpims[form.Get("channel")]; !ok {
+		return nil, apiError("channel_not_found")
+	}
+	return s.history(form.Get("channel"), form), nil
+}
+
+func mpimList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{"groups": sortedChannels(s.mpims, true, false)}, nil
+}
+
+func mpimMark(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if _, ok := s.mpims[form.Get("channel")]; !ok {
+		return nil, apiError("channel_not_found")
+	}
+	return nil, nil
+}
+
+// remindersAdd takes a unix time, or a recurring "every ..." text.
+func remindersAdd(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if len(form.Get("text")) == 0 {
+		return nil, apiError("no_text")
+	}
+	u, err := s.profileUser(form)
+	if err != nil {
+		return nil, err
+	}
+
+	rem := &Reminder{Id: s.newID("Rm"), Creator: s.me.Id, User: u.Id, Text: form.Get("text")}
+	when := form.Get("time")
+	if strings.HasPrefix(when, "every ") {
+		rem.Recurring = true
+	} else if rem.Time, err = strconv.ParseInt(when, 10, 64); err != nil {
+		return nil, apiError("cannot_parse")
+	}
+	s.reminders = append(s.reminders, rem)
+	return map[string]interface{}{"reminder": rem}, nil
+}
+
+func (s *Server) reminder(form url.Values) (int, *Reminder, error) {
+	for i, rem := range s.reminders {
+		if rem.Id == form.Get("reminder") {
+			return i, rem, nil
+		}
+	}
+	return -1, nil, apiError("not_found")
+}
+
+func remindersComplete(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	_, rem, err := s.reminder(form)
+	if err != nil {
+		return nil, err
+	}
+	if rem.Recurring {
+		return nil, apiError("cannot_complete_recurring")
+	}
+	if rem.CompleteTs > 0 {
+		return nil, apiError("already_complete")
+	}
+	rem.CompleteTs = time.Now().Unix()
+	return nil, nil
+}
+
+func remindersDelete(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	i, _, err := s.reminder(form)
+	if err != nil {
+		return nil, err
+	}
+	s.reminders = append(s.reminders[:i], s.reminders[i+1:]...)
+	return nil, nil
+}
+
+func remindersInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	_, rem, err := s.reminder(form)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"reminder": rem}, nil
+}
+
+func remindersList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	reminders := []*Reminder{}
+	for _, rem := range s.reminders {
+		if rem.Creator == s.me.Id || rem.User == s.me.Id {
+			reminders = append(reminders, rem)
+		}
+	}
+	return map[string]interface{}{"reminders": reminders}, nil
+}
+
+func search(messages bool, files bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		query := strings.ToLower(form.Get("query"))
+		if len(query) == 0 {
+			return nil, apiError("no_query")
+		}
+		resp := map[string]interface{}{"query": form.Get("query")}
+
+		if messages {
+			matches := []map[string]interface{}{}
+			for channel, msgs := range s.messages {
+				for _, msg := range msgs {
+					if !strings.Contains(strings.ToLower(msg.Text), query) {
+						continue
+					}
+					name := channel
+					if ch, ok := s.channels[channel]; ok {
+						name = ch.Name
+					}
+					matches = append(matches, map[string]interface{}{
+						"type":    "message",
+						"channel": map[string]string{"id": channel, "name": name},
+						"user":    msg.User,
+						"ts":      msg.Timestamp,
+						"text":    msg.Text,
+					})
+				}
+			}
+			sort.Slice(matches, func(i, j int) bool {
+				return matches[i]["ts"].(string) > matches[j]["ts"].(string)
+			})
+
+			start, end, paging := paginate(len(matches), form)
+			resp["messages"] = map[string]interface{}{"matches": matches[start:end], "paging": paging, "total": len(matches)}
+		}
+
+		if files {
+			matches := []*File{}
+			for _, f := range s.files {
+				if strings.Contains(strings.ToLower(f.Title), query) || strings.Contains(strings.ToLower(f.Name), query) {
+					matches = append(matches, f)
+				}
+			}
+			sort.Slice(matches, func(i, j int) bool {
+				return matches[i].Id < matches[j].Id
+			})
+
+			start, end, paging := paginate(len(matches), form)
+			resp["files"] = map[string]interface{}{"matches": matches[start:end], "paging": paging, "total": len(matches)}
+		}
+
+		return resp, nil
+	}
+}
+
+// starTarget returns the star for the item in form, which is not saved yet.
+func (s *Server) starTarget(form url.Values) (*Star, error) {
+	channel := form.Get("channel")
+	switch {
+	case len(form.Get("file")) > 0:
+		f, ok := s.files[form.Get("file")]
+		if !ok {
+			return nil, apiError("file_not_found")
+		}
+		return &Star{Type: "file", File: f}, nil
+	case len(form.Get("file_comment")) > 0:
+		return nil, apiError("file_comment_not_found")
+	case len(form.Get("timestamp")) > 0:
+		_, msg := s.findMessage(channel, form.Get("timestamp"))
+		if msg == nil {
+			return nil, apiError("message_not_found")
+		}
+		return &Star{Type: "message", Channel: channel, Message: msg}, nil
+	case len(channel) > 0:
+		if err := s.conversation(channel); err != nil {
+			return nil, err
+		}
+		return &Star{Type: "channel", Channel: channel}, nil
+	}
+	return nil, apiError("no_item_specified")
+}
+
+func (st *Star) same(other *Star) bool {
+	return st.Type == other.Type && st.Channel == other.Channel && st.Message == other.Message && st.File == other.File
+}
+
+func starsAdd(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	star, err := s.starTarget(form)
+	if err != nil {
+		return nil, err
+	}
+	for _, old := range s.stars[s.me.Id] {
+		if old.same(star) {
+			return nil, apiError("already_starred")
+		}
+	}
+	if star.Message != nil {
+		star.Message.IsStarred = true
+	}
+	s.stars[s.me.Id] = append(s.stars[s.me.Id], star)
+	return map[string]interface{}{}, nil
+}
+
+func starsRemove(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	star, err := s.starTarget(form)
+	if err != nil {
+		return nil, err
+	}
+	stars := s.stars[s.me.Id]
+	for i, old := range stars {
+		if old.same(star) {
+			if star.Message != nil {
+				star.Message.IsStarred = false
+			}
+			s.stars[s.me.Id] = append(stars[:i], stars[i+1:]...)
+			return map[string]interface{}{}, nil
+		}
+	}
+	return nil, apiError("not_starred")
+}
+
+func starsList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	user := form.Get("user")
+	if len(user) == 0 {
+		user = s.me.Id
+	}
+	items := s.stars[user]
+	if items == nil {
+		items = []*Star{}
+	}
+
+	start, end, paging := paginate(len(items), form)
+	return map[string]interface{}{"items": items[start:end], "paging": paging}, nil
+}
+
+// pinTarget returns the pin for the message or file in form, which is not saved yet.
+func (s *Server) pinTarget(form url.Values) (*Pin, error) {
+	channel := form.Get("channel")
+	if err := s.conversation(channel); err != nil {
+		return nil, err
+	}
+
+	pin := &Pin{Channel: channel, Created: time.Now().Unix(), CreatedBy: s.me.Id}
+	switch {
+	case len(form.Get("file")) > 0:
+		f, ok := s.files[form.Get("file")]
+		if !ok {
+			return nil, apiError("file_not_found")
+		}
+		pin.Type, pin.File = "file", f
+	case len(form.Get("timestamp")) > 0:
+		_, msg := s.findMessage(channel, form.Get("timestamp"))
+		if msg == nil {
+			return nil, apiError("message_not_found")
+		}
+		pin.Type, pin.Message = "message", msg
+	default:
+		return nil, apiError("no_item_specified")
+	}
+	return pin, nil
+}
+
+func (p *Pin) same(other *Pin) bool {
+	return p.Message == other.Message && p.File == other.File
+}
+
+func pinsAdd(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	pin, err := s.pinTarget(form)
+	if err != nil {
+		return nil, err
+	}
+	for _, old := range s.pins[pin.Channel] {
+		if old.same(pin) {
+			return nil, apiError("already_pinned")
+		}
+	}
+	s.pins[pin.Channel] = append(s.pins[pin.Channel], pin)
+	return map[string]interface{}{}, nil
+}
+
+func pinsRemove(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	pin, err := s.pinTarget(form)
+	if err != nil {
+		return nil, err
+	}
+	pins := s.pins[pin.Channel]
+	for i, old := range pins {
+		if old.same(pin) {
+			s.pins[pin.Channel] = append(pins[:i], pins[i+1:]...)
+			return map[string]interface{}{}, nil
+		}
+	}
+	return nil, apiError("no_pin")
+}
+
+func pinsList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	channel := form.Get("channel")
+	if err := s.conversation(channel); err != nil {
+		return nil, err
+	}
+	items := s.pins[channel]
+	if items == nil {
+		items = []*Pin{}
+	}
+	return map[string]interface{}{"items": items}, nil
+}
+
+// reactionTarget returns the reactions and the description of the item in form.
+func (s *Server) reactionTarget(form url.Values) (*[]*Reaction, map[string]interface{}, error) {
+	switch {
+	case len(form.Get("file_comment")) > 0:
+		return nil, nil, apiError("file_comment_not_found")
+	case len(form.Get("file")) > 0:
+		f, ok := s.files[form.Get("file")]
+		if !ok {
+			return nil, nil, apiError("file_not_found")
+		}
+		return &f.Reactions, map[string]interface{}{"type": "file", "file": f}, nil
+	}
+
+	channel := form.Get("channel")
+	if err := s.conversation(channel); err != nil {
+		return nil, nil, err
+	}
+	_, msg := s.findMessage(channel, form.Get("timestamp"))
+	if msg == nil {
+		return nil, nil, apiError("message_not_found")
+	}
+	return &msg.Reactions, map[string]interface{}{"type": "message", "channel": channel, "message": msg}, nil
+}
+
+func reactionsAdd(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	reactions, _, err := s.reactionTarget(form)
+	if err != nil {
+		return nil, err
+	}
+	name := form.Get("name")
+	if len(name) == 0 {
+		return nil, apiError("invalid_name")
+	}
+
+	for _, reaction := range *reactions {
+		if reaction.Name != name {
+			continue
+		}
+		for _, user := range reaction.Users {
+			if user == s.me.Id {
+				return nil, apiError("already_reacted")
+			}
+		}
+		reaction.Users = append(reaction.Users, s.me.Id)
+		reaction.Count++
+		return map[string]interface{}{}, nil
+	}
+
+	*reactions = append(*reactions, &Reaction{Name: name, Count: 1, Users: []string{s.me.Id}})
+	return map[string]interface{}{}, nil
+}
+
+func reactionsRemove(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	reactions, _, err := s.reactionTarget(form)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, reaction := range *reactions {
+		if reaction.Name != form.Get("name") {
+			continue
+		}
+		for j, user := range reaction.Users {
+			if user != s.me.Id {
+				continue
+			}
+			reaction.Users = append(reaction.Users[:j], reaction.Users[j+1:]...)
+			if reaction.Count--; reaction.Count == 0 {
+				*reactions = append((*reactions)[:i], (*reactions)[i+1:]...)
+			}
+			return map[string]interface{}{}, nil
+		}
+	}
+	return nil, apiError("no_reaction")
+}
+
+func reactionsGet(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	_, item, err := s.reactionTarget(form)
+	return item, err
+}
+
+func reactedBy(reactions []*Reaction, user string) bool {
+	for _, reaction := range reactions {
+		for _, u := range reaction.Users {
+			if u == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func reactionsList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	user := form.Get("user")
+	if len(user) == 0 {
+		user = s.me.Id
+	}
+
+	channels := make([]string, 0, len(s.messages))
+	for channel := range s.messages {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	items := []map[string]interface{}{}
+	for _, channel := range channels {
+		for _, msg := range s.messages[channel] {
+			if reactedBy(msg.Reactions, user) {
+				items = append(items, map[string]interface{}{"type": "message", "channel": channel, "message": msg})
+			}
+		}
+	}
+	files := make([]string, 0, len(s.files))
+	for id := range s.files {
+		files = append(files, id)
+	}
+	sort.Strings(files)
+	for _, id := range files {
+		if f := s.files[id]; reactedBy(f.Reactions, user) {
+			items = append(items, map[string]interface{}{"type": "file", "file": f})
+		}
+	}
+
+	start, end, paging := paginate(len(items), form)
+	return map[string]interface{}{"items": items[start:end], "paging": paging}, nil
+}
+
+func usersGetPresence(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	u, err := s.user(form)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"presence": u.Presence, "online": u.Presence == "active"}, nil
+}
+
+func usersInfo(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	u, err := s.user(form)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"user": u}, nil
+}
+
+func usersList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	users := []*User{}
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Id < users[j].Id
+	})
+	return map[string]interface{}{"members": users}, nil
+}
+
+func (s *Server) usergroup(form url.Values) (*Usergroup, error) {
+	for _, g := range s.usergroups {
+		if g.Id == form.Get("usergroup") {
+			return g, nil
+		}
+	}
+	return nil, apiError("no_such_subteam")
+}
+
+// setUsergroup sets the fields in form, and checks the handle is free.
+func (s *Server) setUsergroup(g *Usergroup, form url.Values) error {
+	if handle := form.Get("handle"); len(handle) > 0 {
+		for _, other := range s.usergroups {
+			if other != g && other.Handle == handle {
+				return apiError("name_already_exists")
+			}
+		}
+		g.Handle = handle
+	}
+	if name := form.Get("name"); len(name) > 0 {
+		g.Name = name
+	}
+	if description := form.Get("description"); len(description) > 0 {
+		g.Description = description
+	}
+	if channels := form.Get("channels"); len(channels) > 0 {
+		g.Prefs.Channels = strings.Split(channels, ",")
+	}
+	g.DateUpdate = time.Now().Unix()
+	g.UpdatedBy = s.me.Id
+	return nil
+}
+
+func usergroupsCreate(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if len(form.Get("name")) == 0 {
+		return nil, apiError("invalid_name")
+	}
+	g := &Usergroup{Id: s.newID("S"), TeamId: "T00000001", DateCreate: time.Now().Unix(), CreatedBy: s.me.Id, Users: []string{}}
+	g.Prefs.Channels, g.Prefs.Groups = []string{}, []string{}
+	if err := s.setUsergroup(g, form); err != nil {
+		return nil, err
+	}
+	s.usergroups = append(s.usergroups, g)
+	return map[string]interface{}{"usergroup": g}, nil
+}
+
+func usergroupsEnable(enable bool) methodFunc {
+	return func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+		g, err := s.usergroup(form)
+		if err != nil {
+			return nil, err
+		}
+		if enable == (g.DateDelete == 0) {
+			if enable {
+				return nil, apiError("already_enabled")
+			}
+			return nil, apiError("already_disabled")
+		}
+
+		g.DateDelete = 0
+		if !enable {
+			g.DateDelete = time.Now().Unix()
+		}
+		return map[string]interface{}{"usergroup": g}, nil
+	}
+}
+
+func usergroupsList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	groups := []Usergroup{}
+	for _, g := range s.usergroups {
+		if g.DateDelete > 0 && form.Get("include_disabled") != "true" {
+			continue
+		}
+		group := *g
+		if form.Get("include_users") != "true" {
+			group.Users = nil
+		}
+		groups = append(groups, group)
+	}
+	return map[string]interface{}{"usergroups": groups}, nil
+}
+
+func usergroupsUpdate(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	g, err := s.usergroup(form)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.setUsergroup(g, form); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"usergroup": g}, nil
+}
+
+func usergroupsUsersList(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	g, err := s.usergroup(form)
+	if err != nil {
+		return nil, err
+	}
+	if g.DateDelete > 0 && form.Get("include_disabled") != "true" {
+		return nil, apiError("no_such_subteam")
+	}
+	return map[string]interface{}{"users": g.Users}, nil
+}
+
+func usergroupsUsersUpdate(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	g, err := s.usergroup(form)
+	if err != nil {
+		return nil, err
+	}
+
+	users := strings.Split(form.Get("users"), ",")
+	for _, id := range users {
+		if _, ok := s.users[id]; !ok {
+			return nil, apiError("invalid_users")
+		}
+	}
+	g.Users = users
+	g.DateUpdate = time.Now().Unix()
+	return map[string]interface{}{"usergroup": g}, nil
+}
+
+// profileUser is the user param, or the token user.
+func (s *Server) profileUser(form url.Values) (*User, error) {
+	if len(form.Get("user")) == 0 {
+		return s.me, nil
+	}
+	return s.user(form)
+}
+
+func usersProfileGet(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	u, err := s.profileUser(form)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"profile": u.Profile}, nil
+}
+
+func usersProfileSet(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	u, err := s.profileUser(form)
+	if err != nil {
+		return nil, err
+	}
+	if u != s.me {
+		return nil, apiError("not_admin")
+	}
+
+	// decode over a copy, so a bad profile changes nothing
+	p := u.Profile
+	p.Fields = nil
+	if err = json.Unmarshal([]byte(form.Get("profile")), &p); err != nil {
+		return nil, apiError("invalid_profile")
+	}
+	for id := range p.Fields {
+		if !s.isProfileField(id) {
+			return nil, apiError("invalid_profile")
+		}
+	}
+
+	fields := make(map[string]ProfileField)
+	for id, f := range u.Profile.Fields {
+		fields[id] = f
+	}
+	for id, f := range p.Fields {
+		fields[id] = f
+	}
+	p.Fields = fields
+	u.Profile = p
+	return map[string]interface{}{"profile": u.Profile}, nil
+}
+
+func (s *Server) isProfileField(id string) bool {
+	for _, f := range s.fields {
+		if f.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func usersSetPhoto(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["image"]) == 0 {
+		return nil, apiError("missing_image")
+	}
+	s.me.Profile.ImageOriginal = r.MultipartForm.File["image"][0].Filename
+	return nil, nil
+}
+
+func usersSetActive(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	s.me.Presence = "active"
+	return nil, nil
+}
+
+func usersSetPresence(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	switch form.Get("presence") {
+	case "auto":
+		s.me.Presence = "active"
+	case "away":
+		s.me.Presence = "away"
+	default:
+		return nil, apiError("invalid_presence")
+	}
+	return nil, nil
+}
@@ -0,0 +1,634 @@
+// Package fakeslack is an in-memory fake of the Slack Web API, so the
+// commands of slack-cli can be exercised without a real workspace.
+//
+//	srv := fakeslack.New()
+//	defer srv.Close()
+//	general := srv.AddChannel("general")
+//	// run slack-cli with -api-url=srv.APIURL()
+package fakeslack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Topic struct {
+	Value   string `json:"value"`
+	Creator string `json:"creator"`
+	LastSet int64  `json:"last_set"`
+}
+
+type Channel struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Created    int64    `json:"created"`
+	Creator    string   `json:"creator"`
+	IsChannel  bool     `json:"is_channel,omitempty"`
+	IsGroup    bool     `json:"is_group,omitempty"`
+	IsPrivate  bool     `json:"is_private,omitempty"`
+	IsMpIM     bool     `json:"is_mpim,omitempty"`
+	IsArchived bool     `json:"is_archived"`
+	IsOpen     bool     `json:"is_open"`
+	Members    []string `json:"members"`
+	Topic      Topic    `json:"topic"`
+	Purpose    Topic    `json:"purpose"`
+}
+
+type IM struct {
+	Id      string `json:"id"`
+	IsIM    bool   `json:"is_im"`
+	User    string `json:"user"`
+	Created int64  `json:"created"`
+	IsOpen  bool   `json:"is_open"`
+}
+
+type Profile struct {
+	RealName         string                  `json:"real_name"`
+	DisplayName      string                  `json:"display_name"`
+	FirstName        string                  `json:"first_name"`
+	LastName         string                  `json:"last_name"`
+	Title            string                  `json:"title"`
+	Phone            string                  `json:"phone"`
+	Email            string                  `json:"email"`
+	StatusText       string                  `json:"status_text"`
+	StatusEmoji      string                  `json:"status_emoji"`
+	StatusExpiration int64                   `json:"status_expiration"`
+	ImageOriginal    string                  `json:"image_original,omitempty"`
+	Fields           map[string]ProfileField `json:"fields"`
+}
+
+type ProfileField struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+// TeamProfileField is a custom profile field of the team.
+type TeamProfileField struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+type User struct {
+	Id       string  `json:"id"`
+	Name     string  `json:"name"`
+	Deleted  bool    `json:"deleted"`
+	Profile  Profile `json:"profile"`
+	Presence string  `json:"presence"`
+	DND      DND     `json:"-"`
+}
+
+// DND is the do not disturb schedule and snooze of a user.
+type DND struct {
+	Enabled   bool
+	Start     time.Time
+	End       time.Time
+	SnoozeEnd time.Time
+}
+
+type Message struct {
+	Type        string      `json:"type"`
+	SubType     string      `json:"subtype,omitempty"`
+	Channel     string      `json:"channel,omitempty"`
+	User        string      `json:"user,omitempty"`
+	Text        string      `json:"text"`
+	Timestamp   string      `json:"ts"`
+	ThreadTs    string      `json:"thread_ts,omitempty"`
+	ReplyCount  int         `json:"reply_count,omitempty"`
+	LatestReply string      `json:"latest_reply,omitempty"`
+	IsStarred   bool        `json:"is_starred,omitempty"`
+	Reactions   []*Reaction `json:"reactions,omitempty"`
+}
+
+// isHiddenReply reports whether msg is a thread reply which only shows in its thread.
+func (msg *Message) isHiddenReply() bool {
+	return len(msg.ThreadTs) > 0 && msg.ThreadTs != msg.Timestamp && msg.SubType != "thread_broadcast"
+}
+
+type File struct {
+	Id        string      `json:"id"`
+	Created   int64       `json:"created"`
+	Name      string      `json:"name"`
+	Title     string      `json:"title"`
+	Filetype  string      `json:"filetype"`
+	User      string      `json:"user"`
+	Size      int         `json:"size"`
+	Channels  []string    `json:"channels"`
+	Reactions []*Reaction `json:"reactions,omitempty"`
+	Content   string      `json:"-"`
+}
+
+type Reaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+type Paging struct {
+	Count int `json:"count"`
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+// Star is an item starred by a user.
+type Star struct {
+	Type    string   `json:"type"`
+	Channel string   `json:"channel,omitempty"`
+	Message *Message `json:"message,omitempty"`
+	File    *File    `json:"file,omitempty"`
+}
+
+// Pin is an item pinned to a channel.
+type Pin struct {
+	Type      string   `json:"type"`
+	Channel   string   `json:"channel"`
+	Created   int64    `json:"created"`
+	CreatedBy string   `json:"created_by"`
+	Message   *Message `json:"message,omitempty"`
+	File      *File    `json:"file,omitempty"`
+}
+
+type Reminder struct {
+	Id         string `json:"id"`
+	Creator    string `json:"creator"`
+	User       string `json:"user"`
+	Text       string `json:"text"`
+	Recurring  bool   `json:"recurring"`
+	Time       int64  `json:"time,omitempty"`
+	CompleteTs int64  `json:"complete_ts,omitempty"`
+}
+
+type Usergroup struct {
+	Id          string `json:"id"`
+	TeamId      string `json:"team_id"`
+	Name        string `json:"name"`
+	Handle      string `json:"handle"`
+	Description string `json:"description"`
+	DateCreate  int64  `json:"date_create"`
+	DateUpdate  int64  `json:"date_update"`
+	DateDelete  int64  `json:"date_delete"`
+	CreatedBy   string `json:"created_by"`
+	UpdatedBy   string `json:"updated_by"`
+	Prefs       struct {
+		Channels []string `json:"channels"`
+		Groups   []string `json:"groups"`
+	} `json:"prefs"`
+	Users []string `json:"users,omitempty"`
+}
+
+// Login is an access log entry of team.accessLogs.
+type Login struct {
+	UserId    string `json:"user_id"`
+	Username  string `json:"username"`
+	DateFirst int64  `json:"date_first"`
+	DateLast  int64  `json:"date_last"`
+	Count     int    `json:"count"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	ISP       string `json:"isp"`
+	Country   string `json:"country"`
+	Region    string `json:"region"`
+}
+
+// Server is a fake Slack Web API served by httptest.
+type Server struct {
+	*httptest.Server
+
+	// Token, if set, must be sent with every request.
+	Token string
+
+	m          sync.Mutex
+	nextID     int
+	start      int64
+	clock      int
+	me         *User
+	users      map[string]*User
+	channels   map[string]*Channel
+	ims        map[string]*IM
+	mpims      map[string]*Channel
+	messages   map[string][]*Message
+	files      map[string]*File
+	stars      map[string][]*Star
+	pins       map[string][]*Pin
+	emoji      map[string]string
+	logins     []*Login
+	fields     []*TeamProfileField
+	reminders  []*Reminder
+	usergroups []*Usergroup
+}
+
+type methodFunc func(s *Server, form url.Values, r *http.Request) (map[string]interface{}, error)
+
+// apiError is returned in the error field of a failed response.
+type apiError string
+
+func (e apiError) Error() string {
+	return string(e)
+}
+
+// New starts a fake server with one user, the one the token belongs to.
+func New() *Server {
+	s := &Server{
+		start:    time.Now().Unix(),
+		users:    make(map[string]*User),
+		channels: make(map[string]*Channel),
+		ims:      make(map[string]*IM),
+		mpims:    make(map[string]*Channel),
+		messages: make(map[string][]*Message),
+		files:    make(map[string]*File),
+		stars:    make(map[string][]*Star),
+		pins:     make(map[string][]*Pin),
+		emoji:    map[string]string{"shipit": "https://emoji.example.com/shipit.png"},
+	}
+	s.me = s.AddUser("me")
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// APIURL is the endpoint to pass to -api-url.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+// Me is the user the token belongs to.
+func (s *Server) Me() *User {
+	return s.me
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%08d", prefix, s.nextID)
+}
+
+func (s *Server) newTimestamp() string {
+	s.clock++
+	return fmt.Sprintf("%d.%06d", s.start, s.clock)
+}
+
+// AddUser adds a user to the team.
+func (s *Server) AddUser(name string) *User {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	u := &User{Id: s.newID("U"), Name: name, Presence: "active"}
+	u.Profile.RealName = name
+	s.users[u.Id] = u
+	return u
+}
+
+func (s *Server) addChannel(name string, group bool) *Channel {
+	prefix := "C"
+	if group {
+		prefix = "G"
+	}
+	ch := &Channel{
+		Id:        s.newID(prefix),
+		Name:      name,
+		Created:   s.start,
+		Creator:   s.me.Id,
+		IsChannel: !group,
+		IsGroup:   group,
+		IsPrivate: group,
+		IsOpen:    true,
+		Members:   []string{s.me.Id},
+	}
+	s.channels[ch.Id] = ch
+	return ch
+}
+
+// AddChannel adds a public channel the token user is a member of.
+func (s *Server) AddChannel(name string) *Channel {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.addChannel(name, false)
+}
+
+// AddGroup adds a private group the token user is a member of.
+func (s *Server) AddGroup(name string) *Channel {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.addChannel(name, true)
+}
+
+// AddProfileField adds a custom text field to the profiles of the team.
+func (s *Server) AddProfileField(label string) *TeamProfileField {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	f := &TeamProfileField{Id: s.newID("Xf"), Label: label, Type: "text"}
+	s.fields = append(s.fields, f)
+	return f
+}
+
+// SetDND schedules do not disturb for user from start to end.
+func (s *Server) SetDND(user string, start time.Time, end time.Time) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if u, ok := s.users[user]; ok {
+		u.DND.Enabled = true
+		u.DND.Start = start
+		u.DND.End = end
+	}
+}
+
+// AddLogin logs a login of user at time from ip with userAgent.
+func (s *Server) AddLogin(user string, ip string, userAgent string, at time.Time) *Login {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	l := &Login{
+		UserId:    user,
+		DateFirst: at.Unix(),
+		DateLast:  at.Unix(),
+		Count:     1,
+		IP:        ip,
+		UserAgent: userAgent,
+		Country:   "US",
+	}
+	if u, ok := s.users[user]; ok {
+		l.Username = u.Name
+	}
+	s.logins = append(s.logins, l)
+	return l
+}
+
+// AddMessage posts text by user to a channel, group or IM.
+func (s *Server) AddMessage(channel string, user string, text string) *Message {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.addMessage(channel, user, text)
+}
+
+func (s *Server) addMessage(channel string, user string, text string) *Message {
+	msg := &Message{Type: "message", Channel: channel, User: user, Text: text, Timestamp: s.newTimestamp()}
+	s.messages[channel] = append(s.messages[channel], msg)
+	return msg
+}
+
+// AddReply posts text by user in the thread of the message at threadTs.
+func (s *Server) AddReply(channel string, threadTs string, user string, text string) *Message {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	msg, _ := s.addReply(channel, threadTs, user, text)
+	return msg
+}
+
+func (s *Server) addReply(channel string, threadTs string, user string, text string) (*Message, error) {
+	_, parent := s.findMessage(channel, threadTs)
+	if parent == nil {
+		return nil, apiError("thread_not_found")
+	}
+
+	msg := s.addMessage(channel, user, text)
+	msg.ThreadTs = threadTs
+	parent.ThreadTs = threadTs
+	parent.ReplyCount++
+	parent.LatestReply = msg.Timestamp
+	return msg, nil
+}
+
+// Messages returns a copy of the messages in a channel, oldest first.
+func (s *Server) Messages(channel string) []Message {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	msgs := make([]Message, 0, len(s.messages[channel]))
+	for _, msg := range s.messages[channel] {
+		msgs = append(msgs, *msg)
+	}
+	return msgs
+}
+
+// Channel returns a copy of a channel or group.
+func (s *Server) Channel(id string) (Channel, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	ch, ok := s.channels[id]
+	if !ok {
+		return Channel{}, false
+	}
+	return *ch, true
+}
+
+// File returns a copy of an uploaded file.
+func (s *Server) File(id string) (File, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	f, ok := s.files[id]
+	if !ok {
+		return File{}, false
+	}
+	return *f, true
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := s.call(path.Base(r.URL.Path), r.Form, r)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// call runs a method and encodes its response while still holding the lock,
+// since the response points into the state of the server.
+func (s *Server) call(method string, form url.Values, r *http.Request) []byte {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	resp, err := s.dispatch(method, form, r)
+	if err != nil {
+		resp = map[string]interface{}{"ok": false, "error": err.Error()}
+	} else {
+		if resp == nil {
+			resp = make(map[string]interface{})
+		}
+		resp["ok"] = true
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(map[string]interface{}{"ok": false, "error": err.Error()})
+	}
+	return append(data, '\n')
+}
+
+func (s *Server) dispatch(method string, form url.Values, r *http.Request) (map[string]interface{}, error) {
+	f, ok := methods[method]
+	if !ok {
+		return nil, apiError("unknown_method")
+	}
+
+	if len(s.Token) > 0 && form.Get("token") != s.Token {
+		return nil, apiError("invalid_auth")
+	}
+
+	return f(s, form, r)
+}
+
+func (s *Server) channel(form url.Values, group bool) (*Channel, error) {
+	ch, ok := s.channels[form.Get("channel")]
+	if !ok || ch.IsGroup != group {
+		return nil, apiError("channel_not_found")
+	}
+	return ch, nil
+}
+
+func (s *Server) user(form url.Values) (*User, error) {
+	u, ok := s.users[form.Get("user")]
+	if !ok {
+		return nil, apiError("user_not_found")
+	}
+	return u, nil
+}
+
+func (s *Server) channelByName(name string) *Channel {
+	for _, ch := range s.channels {
+		if ch.Name == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+func (s *Server) findMessage(channel string, ts string) (int, *Message) {
+	for i, msg := range s.messages[channel] {
+		if msg.Timestamp == ts {
+			return i, msg
+		}
+	}
+	return -1, nil
+}
+
+// parseTimestamp turns a slack timestamp into microseconds, so it can be compared exactly.
+func parseTimestamp(ts string) int64 {
+	seps := strings.SplitN(ts, ".", 2)
+	sec, _ := strconv.ParseInt(seps[0], 10, 64)
+	var usec int64
+	if len(seps) == 2 {
+		frac := (seps[1] + "000000")[:6]
+		usec, _ = strconv.ParseInt(frac, 10, 64)
+	}
+	return sec*1000000 + usec
+}
+
+func intValue(form url.Values, key string, defValue int) int {
+	v, err := strconv.Atoi(form.Get(key))
+	if err != nil {
+		return defValue
+	}
+	return v
+}
+
+// history returns messages between oldest and latest, newest first.
+func (s *Server) history(channel string, form url.Values) map[string]interface{} {
+	latest := int64(1) << 62
+	if v := form.Get("latest"); len(v) > 0 {
+		latest = parseTimestamp(v)
+	}
+	oldest := parseTimestamp(form.Get("oldest"))
+	count := intValue(form, "count", 100)
+
+	msgs := s.messages[channel]
+	matches := []*Message{}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		ts := parseTimestamp(msgs[i].Timestamp)
+		if ts >= latest || ts <= oldest || msgs[i].isHiddenReply() {
+			continue
+		}
+		matches = append(matches, msgs[i])
+	}
+
+	hasMore := len(matches) > count
+	if hasMore {
+		matches = matches[:count]
+	}
+
+	return map[string]interface{}{
+		"latest":   form.Get("latest"),
+		"messages": matches,
+		"has_more": hasMore,
+	}
+}
+
+func paginate(total int, form url.Values) (int, int, Paging) {
+	count := intValue(form, "count", 100)
+	if count <= 0 {
+		count = 100
+	}
+	page := intValue(form, "page", 1)
+	if page <= 0 {
+		page = 1
+	}
+
+	start := (page - 1) * count
+	if start > total {
+		start = total
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+	return start, end, Paging{Count: count, Total: total, Page: page, Pages: (total + count - 1) / count}
+}
+
+// cursorPage pages total items with the index of the next item as cursor.
+func cursorPage(total int, form url.Values) (int, int, string) {
+	start := intValue(form, "cursor", 0)
+	if start > total {
+		start = total
+	}
+	limit := intValue(form, "limit", 100)
+	if limit <= 0 {
+		limit = 100
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	next := ""
+	if end < total {
+		next = strconv.Itoa(end)
+	}
+	return start, end, next
+}
+
+func sortedChannels(channels map[string]*Channel, group bool, excludeArchived bool) []*Channel {
+	chs := []*Channel{}
+	for _, ch := range channels {
+		if ch.IsGroup != group || (excludeArchived && ch.IsArchived) {
+			continue
+		}
+		chs = append(chs, ch)
+	}
+	sort.Slice(chs, func(i, j int) bool {
+		return chs[i].Id < chs[j].Id
+	})
+	return chs
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/siddontang/slack-cli/fakeslack"
+)
+
+// newTestSlack starts a fake workspace and a session pointed at it with -api-url.
+func newTestSlack(t *testing.T) (*Slack, *fakeslack.Server) {
+	srv := fakeslack.New()
+	t.Cleanup(srv.Close)
+
+	defaultURL := *apiURL
+	*apiURL = srv.APIURL()
+	rt, err := newTransport()
+	*apiURL = defaultURL
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Slack{s: newAPIClient("xoxp-test", rt)}
+
+	dir := t.TempDir()
+	s.auditLog = newAuditLog(filepath.Join(dir, "audit.jsonl"))
+	s.drafts = newDraftStore(filepath.Join(dir, "drafts.json"))
+	return s, srv
+}
+
+// mustRun runs a command and decodes its result into out, if not nil, the way
+// it is printed.
+func mustRun(t *testing.T, s *Slack, cmd string, out interface{}, args ...string) {
+	t.Helper()
+
+	v, err := s.run(cmd, args)
+	if err != nil {
+		t.Fatalf("%s: %v", cmd, err)
+	}
+	if out == nil {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("%s: %v", cmd, err)
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		t.Fatalf("%s: %v", cmd, err)
+	}
+}
+
+func TestFakeChannels(t *testing.T) {
+	s, srv := newTestSlack(t)
+
+	mustRun(t, s, "channels.create", nil, "name=ops")
+	id, err := s.resolveChannel(context.Background(), "#ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustRun(t, s, "channels.setTopic", nil, "channel=#ops", `topic="deploys and incidents"`)
+	srv.AddMessage(id, srv.Me().Id, "hello")
+
+	var h struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+	mustRun(t, s, "channels.history", &h, "channel="+id)
+	if len(h.Messages) != 1 || h.Messages[0].Text != "hello" {
+		t.Fatalf("history %+v", h.Messages)
+	}
+
+	mustRun(t, s, "channels.archive", nil, "channel="+id)
+	ch, ok := srv.Channel(id)
+	if !ok {
+		t.Fatalf("channel %s not found", id)
+	}
+	if ch.Topic.Value != "deploys and incidents" || !ch.IsArchived {
+		t.Fatalf("channel %+v", ch)
+	}
+}
+
+func TestFakeHistoryIndex(t *testing.T) {
+	s, srv := newTestSlack(t)
+	ops := srv.AddChannel("ops")
+	srv.AddMessage(ops.Id, srv.Me().Id, "deploy done")
+
+	index, err := openIndex(filepath.Join(t.TempDir(), "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.index = index
+
+	mustRun(t, s, "channels.history", nil, "channel=#ops")
+
+	var found struct {
+		Messages struct {
+			Matches []struct {
+				Channel string `json:"channel"`
+			} `json:"matches"`
+		} `json:"messages"`
+	}
+	mustRun(t, s, "local.search", &found, "query=deploy", "in=#ops")
+	if len(found.Messages.Matches) != 1 || found.Messages.Matches[0].Channel != ops.Id {
+		t.Fatalf("matches %+v", found.Messages.Matches)
+	}
+}
+
+func TestFakeChat(t *testing.T) {
+	s, srv := newTestSlack(t)
+	general := srv.AddChannel("general")
+
+	var posted struct {
+		Channel string `json:"channel"`
+		Ts      string `json:"ts"`
+	}
+	mustRun(t, s, "chat.postMessage", &posted, "channel=#general", `text="hello world"`)
+	if posted.Channel != general.Id {
+		t.Fatalf("posted to %s, not %s", posted.Channel, general.Id)
+	}
+
+	mustRun(t, s, "chat.reply", nil, "channel="+general.Id, "ts="+posted.Ts, "text=on it")
+	mustRun(t, s, "chat.update", nil, "channel="+general.Id, "ts="+posted.Ts, "text=edited")
+
+	msgs := srv.Messages(general.Id)
+	if len(msgs) != 2 || msgs[0].Text != "edited" || msgs[1].ThreadTs != posted.Ts {
+		t.Fatalf("messages %+v", msgs)
+	}
+
+	mustRun(t, s, "chat.delete", nil, "channel="+general.Id, "ts="+msgs[1].Timestamp)
+	if msgs = srv.Messages(general.Id); len(msgs) != 1 {
+		t.Fatalf("messages %+v", msgs)
+	}
+}
+
+func TestFakeIM(t *testing.T) {
+	s, srv := newTestSlack(t)
+	alice := srv.AddUser("alice")
+
+	var opened struct {
+		Channel struct {
+			Id string `json:"id"`
+		} `json:"channel"`
+	}
+	mustRun(t, s, "im.open", &opened, "user="+alice.Id)
+	if len(opened.Channel.Id) == 0 {
+		t.Fatal("im.open returned no channel")
+	}
+
+	srv.AddMessage(opened.Channel.Id, alice.Id, "ping")
+
+	var h struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+	mustRun(t, s, "im.history", &h, "channel="+opened.Channel.Id)
+	if len(h.Messages) != 1 || h.Messages[0].Text != "ping" {
+		t.Fatalf("history %+v", h.Messages)
+	}
+
+	var closed struct {
+		AlreadyClosed bool `json:"already_closed"`
+	}
+	mustRun(t, s, "im.close", &closed, "channel="+opened.Channel.Id)
+	if closed.AlreadyClosed {
+		t.Fatal("im was closed already")
+	}
+}
+
+func TestFakeFiles(t *testing.T) {
+	s, srv := newTestSlack(t)
+	general := srv.AddChannel("general")
+
+	var uploaded struct {
+		File struct {
+			Id string `json:"id"`
+		} `json:"file"`
+	}
+	mustRun(t, s, "files.upload", &uploaded, "channels="+general.Id, "content=uptime 42d", "filename=uptime.txt")
+	f, ok := srv.File(uploaded.File.Id)
+	if !ok || f.Content != "uptime 42d" || f.Name != "uptime.txt" {
+		t.Fatalf("file %+v", f)
+	}
+
+	var info struct {
+		File struct {
+			Id string `json:"id"`
+		} `json:"file"`
+	}
+	mustRun(t, s, "files.info", &info, "file="+f.Id)
+	if info.File.Id != f.Id {
+		t.Fatalf("info of %s, not %s", info.File.Id, f.Id)
+	}
+
+	mustRun(t, s, "files.delete", nil, "file="+f.Id)
+	if _, ok = srv.File(f.Id); ok {
+		t.Fatalf("file %s not deleted", f.Id)
+	}
+}
+
+func TestFakeUsers(t *testing.T) {
+	s, srv := newTestSlack(t)
+	alice := srv.AddUser("alice")
+
+	var users []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+	mustRun(t, s, "users.list", &users)
+	if len(users) != 2 {
+		t.Fatalf("users %+v", users)
+	}
+
+	var info struct {
+		Name string `json:"name"`
+	}
+	mustRun(t, s, "users.info", &info, "user="+alice.Id)
+	if info.Name != "alice" {
+		t.Fatalf("info %+v", info)
+	}
+
+	var profile struct {
+		Profile struct {
+			Title string `json:"title"`
+		} `json:"profile"`
+	}
+	mustRun(t, s, "users.profile.set", nil, `title="Site Reliability"`)
+	mustRun(t, s, "users.profile.get", &profile)
+	if profile.Profile.Title != "Site Reliability" {
+		t.Fatalf("profile %+v", profile)
+	}
+}
+
+func TestFakeDescribe(t *testing.T) {
+	s, srv := newTestSlack(t)
+	ops := srv.AddChannel("ops")
+	alice := srv.AddUser("alice")
+	msg := srv.AddMessage(ops.Id, alice.Id, "deploy done")
+
+	ctx := context.Background()
+	tests := []struct {
+		cmd    string
+		params map[string]string
+		want   string
+	}{
+		{"channels.archive", map[string]string{"channel": "#ops"}, "archive #ops (" + ops.Id + ")"},
+		{"channels.kick", map[string]string{"channel": "#ops", "user": "@alice"}, "kick @alice (" + alice.Id + ") from #ops (" + ops.Id + ")"},
+		{"chat.delete", map[string]string{"channel": "#ops", "ts": msg.Timestamp}, "delete message " + msg.Timestamp + " in #ops (" + ops.Id + ")\n\t@alice (" + alice.Id + "): deploy done"},
+	}
+
+	for _, tt := range tests {
+		if got := s.describe(ctx, tt.cmd, tt.params); got != tt.want {
+			t.Errorf("describe(%s) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestFakeDryRun(t *testing.T) {
+	s, srv := newTestSlack(t)
+	general := srv.AddChannel("general")
+	srv.AddMessage(general.Id, srv.Me().Id, "hello")
+
+	mustRun(t, s, "chat.postMessage", nil, "channel=#general", "text=dry", "dry_run=1")
+	if msgs := srv.Messages(general.Id); len(msgs) != 1 {
+		t.Fatalf("messages %+v", msgs)
+	}
+
+	var h struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+	mustRun(t, s, "channels.history", &h, "channel=#general", "dry_run=1")
+	if len(h.Messages) != 1 || h.Messages[0].Text != "hello" {
+		t.Fatalf("history %+v", h.Messages)
+	}
+}
+
+func TestFakeAudit(t *testing.T) {
+	s, srv := newTestSlack(t)
+	ops := srv.AddChannel("ops")
+	srv.AddChannel("dev")
+
+	mustRun(t, s, "channels.archive", nil, "channel=#ops")
+	mustRun(t, s, "channels.setTopic", nil, "channel=#dev", "topic=builds")
+
+	var shown struct {
+		Entries []struct {
+			SlackUser string            `json:"slack_user"`
+			Command   string            `json:"command"`
+			Params    map[string]string `json:"params"`
+		} `json:"entries"`
+	}
+	mustRun(t, s, "audit.show", &shown, "channel=#ops", "user=@"+srv.Me().Name)
+	if len(shown.Entries) != 1 || shown.Entries[0].Command != "channels.archive" || shown.Entries[0].Params["channel"] != ops.Id {
+		t.Fatalf("entries %+v", shown.Entries)
+	}
+	if shown.Entries[0].SlackUser != srv.Me().Id {
+		t.Fatalf("slack user %s, not %s", shown.Entries[0].SlackUser, srv.Me().Id)
+	}
+}
+
+func TestFakeUndo(t *testing.T) {
+	s, srv := newTestSlack(t)
+	ops := srv.AddChannel("ops")
+	alice := srv.AddUser("alice")
+	msg := srv.AddMessage(ops.Id, srv.Me().Id, "deploy started")
+
+	members := func() []string {
+		ch, _ := srv.Channel(ops.Id)
+		return ch.Members
+	}
+	hasMember := func(user string) bool {
+		for _, m := range members() {
+			if m == user {
+				return true
+			}
+		}
+		return false
+	}
+
+	mustRun(t, s, "channels.setTopic", nil, "channel=#ops", "topic=deploys")
+	mustRun(t, s, "channels.setTopic", nil, "channel=#ops", "topic=incidents")
+	if _, err := s.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if ch, _ := srv.Channel(ops.Id); ch.Topic.Value != "deploys" {
+		t.Fatalf("topic %q after undo", ch.Topic.Value)
+	}
+
+	mustRun(t, s, "chat.update", nil, "channel=#ops", "ts="+msg.Timestamp, "text=deploy done")
+	if _, err := s.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := srv.Messages(ops.Id); len(msgs) != 1 || msgs[0].Text != "deploy started" {
+		t.Fatalf("messages %+v after undo", msgs)
+	}
+
+	mustRun(t, s, "channels.invite", nil, "channel=#ops", "user=@alice")
+	mustRun(t, s, "channels.kick", nil, "channel=#ops", "user=@alice")
+	if hasMember(alice.Id) {
+		t.Fatalf("alice is still in %v", members())
+	}
+	if _, err := s.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if !hasMember(alice.Id) {
+		t.Fatalf("alice is not back in %v", members())
+	}
+
+	mustRun(t, s, "channels.archive", nil, "channel=#ops")
+	if _, err := s.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if ch, _ := srv.Channel(ops.Id); ch.IsArchived {
+		t.Fatal("channel is still archived")
+	}
+
+	// undo commands are audited like the others, with resolved ids
+	var shown struct {
+		Entries []struct {
+			Command string            `json:"command"`
+			Params  map[string]string `json:"params"`
+		} `json:"entries"`
+	}
+	mustRun(t, s, "audit.show", &shown, "channel=#ops", "count=100")
+	var cmds []string
+	for _, e := range shown.Entries {
+		if e.Params["channel"] != ops.Id {
+			t.Fatalf("entry %+v not resolved", e)
+		}
+		cmds = append(cmds, e.Command)
+	}
+	want := []string{
+		"channels.setTopic", "channels.setTopic", "channels.setTopic",
+		"chat.update", "chat.update",
+		"channels.invite", "channels.kick", "channels.invite",
+		"channels.archive", "channels.unarchive",
+	}
+	if !reflect.DeepEqual(cmds, want) {
+		t.Fatalf("audited %v, want %v", cmds, want)
+	}
+}
+
+func TestFakeConversations(t *testing.T) {
+	s, srv := newTestSlack(t)
+	srv.AddUser("alice")
+	srv.AddUser("bob")
+	srv.AddChannel("general")
+
+	var created struct {
+		Channel struct {
+			Id string `json:"id"`
+		} `json:"channel"`
+	}
+	mustRun(t, s, "conversations.create", &created, "name=ops")
+	ops := created.Channel.Id
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		srv.AddMessage(ops, srv.Me().Id, text)
+	}
+
+	type history struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+		HasMore    bool   `json:"has_more"`
+		NextCursor string `json:"next_cursor"`
+	}
+	var first, second, all history
+	mustRun(t, s, "conversations.history", &first, "channel=#ops", "limit=2")
+	if len(first.Messages) != 2 || !first.HasMore || len(first.NextCursor) == 0 {
+		t.Fatalf("first page %+v", first)
+	}
+	mustRun(t, s, "conversations.history", &second, "channel=#ops", "limit=2", "cursor="+first.NextCursor)
+	if len(second.Messages) != 2 || second.Messages[0].Text == first.Messages[0].Text {
+		t.Fatalf("second page %+v after %+v", second, first)
+	}
+	mustRun(t, s, "conversations.history", &all, "channel=#ops", "limit=2", "all=true")
+	if len(all.Messages) != 5 || len(all.NextCursor) > 0 {
+		t.Fatalf("all pages %+v", all)
+	}
+
+	var list struct {
+		Channels []struct {
+			Id string `json:"id"`
+		} `json:"channels"`
+	}
+	mustRun(t, s, "conversations.list", &list, "limit=1", "all=true")
+	if len(list.Channels) != 2 {
+		t.Fatalf("channels %+v", list.Channels)
+	}
+
+	mustRun(t, s, "conversations.leave", nil, "channel=#ops")
+	mustRun(t, s, "conversations.join", nil, "channel="+ops)
+	mustRun(t, s, "conversations.mark", nil, "channel=#ops", "ts=1")
+
+	var opened struct {
+		Channel struct {
+			Id string `json:"id"`
+		} `json:"channel"`
+	}
+	mustRun(t, s, "conversations.open", &opened, "users=@alice")
+	mustRun(t, s, "conversations.close", nil, "channel="+opened.Channel.Id)
+	mustRun(t, s, "conversations.open", &opened, "users=@alice,@bob")
+	mustRun(t, s, "conversations.close", nil, "channel="+opened.Channel.Id)
+
+	ch, _ := srv.Channel(ops)
+	if len(ch.Members) != 1 || ch.Members[0] != srv.Me().Id {
+		t.Fatalf("members %v", ch.Members)
+	}
+}
+
+func TestFakeAccessLogs(t *testing.T) {
+	s, srv := newTestSlack(t)
+	alice := srv.AddUser("alice")
+	bob := srv.AddUser("bob")
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1500; i++ {
+		user := alice.Id
+		if i%2 == 1 {
+			user = bob.Id
+		}
+		srv.AddLogin(user, "10.0.0.1", "slack-cli", start.Add(time.Duration(i)*time.Hour))
+	}
+
+	var logs struct {
+		Logins []struct {
+			UserId string `json:"user_id"`
+		} `json:"logins"`
+	}
+	mustRun(t, s, "team.accessLogs", &logs, "user=@alice")
+	if len(logs.Logins) != 750 {
+		t.Fatalf("%d logins of alice", len(logs.Logins))
+	}
+
+	mustRun(t, s, "team.accessLogs", &logs, "since="+strconv.FormatInt(start.Add(1400*time.Hour).Unix(), 10))
+	if len(logs.Logins) != 100 {
+		t.Fatalf("%d logins since the 1400th", len(logs.Logins))
+	}
+}
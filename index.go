@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nlopes/slack"
+)
+
+// highlight markers, the same ones Slack uses when highlight is enabled in search
+const (
+	highlightStart = "\ue000"
+	highlightEnd   = "\ue001"
+)
+
+type indexedMessage struct {
+	Channel string `json:"channel"`
+	User    string `json:"user"`
+	Ts      string `json:"ts"`
+	Text    string `json:"text"`
+}
+
+// indexSaveInterval is how often a growing index is written, the rest is
+// written by flush when the session ends.
+const indexSaveInterval = 30 * time.Second
+
+// localIndex is an inverted index over messages fetched by the *.history commands,
+// persisted as a JSON file so it survives between sessions.
+type localIndex struct {
+	path string
+
+	dirty bool
+	saved time.Time
+
+	// key is channel:ts
+	Messages map[string]*indexedMessage `json:"messages"`
+	// term -> message key -> term frequency
+	Postings map[string]map[string]int `json:"postings"`
+}
+
+func openIndex(path string) (*localIndex, error) {
+	idx := &localIndex{
+		path:     path,
+		Messages: make(map[string]*indexedMessage),
+		Postings: make(map[string]map[string]int),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("load index %s: %s", path, err.Error())
+	}
+	return idx, nil
+}
+
+func (idx *localIndex) save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp := idx.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// flush saves the index if it changed since the last save.
+func (idx *localIndex) flush() error {
+	if !idx.dirty {
+		return nil
+	}
+	if err := idx.save(); err != nil {
+		return err
+	}
+	idx.dirty = false
+	idx.saved = time.Now()
+	return nil
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (idx *localIndex) remove(key string) {
+	m, ok := idx.Messages[key]
+	if !ok {
+		return
+	}
+	for _, term := range tokenize(m.Text) {
+		delete(idx.Postings[term], key)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Messages, key)
+}
+
+func (idx *localIndex) add(channel string, msgs []Message) error {
+	for _, msg := range msgs {
+		if len(msg.Timestamp) == 0 || len(msg.Text) == 0 {
+			continue
+		}
+
+		key := channel + ":" + msg.Timestamp
+		idx.remove(key)
+
+		idx.Messages[key] = &indexedMessage{
+			Channel: channel,
+			User:    msg.User,
+			Ts:      msg.Timestamp,
+			Text:    msg.Text,
+		}
+
+		for _, term := range tokenize(msg.Text) {
+			postings, ok := idx.Postings[term]
+			if !ok {
+				postings = make(map[string]int)
+				idx.Postings[term] = postings
+			}
+			postings[key]++
+		}
+		idx.dirty = true
+	}
+
+	// the whole index is rewritten, so don't do it for every page of history
+	if time.Since(idx.saved) < indexSaveInterval {
+		return nil
+	}
+	return idx.flush()
+}
+
+type localSearchParameters struct {
+	From          string
+	In            string
+	After         float64
+	Before        float64
+	Sort          string
+	SortDirection string
+	Highlight     bool
+	Count         int
+	Page          int
+}
+
+type localMatch struct {
+	Type    string  `json:"type"`
+	Channel string  `json:"channel"`
+	User    string  `json:"user"`
+	Ts      string  `json:"ts"`
+	Text    string  `json:"text"`
+	Score   float64 `json:"score"`
+}
+
+type localMessages struct {
+	Matches []localMatch `json:"matches"`
+	Paging  slack.Paging `json:"paging"`
+	Total   int          `json:"total"`
+}
+
+func (idx *localIndex) search(query string, p localSearchParameters) *localMessages {
+	terms := tokenize(query)
+
+	// every term must match, scored with tf-idf
+	scores := make(map[string]float64)
+	if len(terms) == 0 {
+		// only filters, every message is a candidate and newer ones come first
+		for key := range idx.Messages {
+			scores[key] = 0
+		}
+	}
+	for i, term := range terms {
+		postings := idx.Postings[term]
+		idf := math.Log(1 + float64(len(idx.Messages))/float64(1+len(postings)))
+
+		next := make(map[string]float64)
+		for key, tf := range postings {
+			if _, ok := scores[key]; i > 0 && !ok {
+				continue
+			}
+			next[key] = scores[key] + float64(tf)*idf
+		}
+		scores = next
+	}
+
+	matches := make([]localMatch, 0, len(scores))
+	for key, score := range scores {
+		m := idx.Messages[key]
+		if len(p.From) > 0 && m.User != p.From {
+			continue
+		}
+		if len(p.In) > 0 && m.Channel != p.In {
+			continue
+		}
+
+		ts, _ := strconv.ParseFloat(m.Ts, 64)
+		if p.After > 0 && ts < p.After {
+			continue
+		}
+		if p.Before > 0 && ts >= p.Before {
+			continue
+		}
+
+		text := m.Text
+		if p.Highlight {
+			text = highlightTerms(text, terms)
+		}
+
+		matches = append(matches, localMatch{
+			Type:    "message",
+			Channel: m.Channel,
+			User:    m.User,
+			Ts:      m.Ts,
+			Text:    text,
+			Score:   score,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if p.SortDirection == "asc" {
+			a, b = b, a
+		}
+		if p.Sort == "timestamp" || a.Score == b.Score {
+			return a.Ts > b.Ts
+		}
+		return a.Score > b.Score
+	})
+
+	count := p.Count
+	if count <= 0 {
+		count = slack.DEFAULT_SEARCH_COUNT
+	}
+	page := p.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	res := &localMessages{
+		Total: len(matches),
+		Paging: slack.Paging{
+			Count: count,
+			Total: len(matches),
+			Page:  page,
+			Pages: (len(matches) + count - 1) / count,
+		},
+	}
+
+	start := (page - 1) * count
+	if start < len(matches) {
+		end := start + count
+		if end > len(matches) {
+			end = len(matches)
+		}
+		res.Matches = matches[start:end]
+	}
+	return res
+}
+
+func highlightTerms(text string, terms []string) string {
+	want := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		want[term] = true
+	}
+
+	var buf []rune
+	var word []rune
+	flush := func() {
+		if want[strings.ToLower(string(word))] {
+			buf = append(buf, []rune(highlightStart)...)
+			buf = append(buf, word...)
+			buf = append(buf, []rune(highlightEnd)...)
+		} else {
+			buf = append(buf, word...)
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		buf = append(buf, r)
+	}
+	flush()
+
+	return string(buf)
+}
+
+// parseDateParam accepts YYYY-MM-DD in local time or a Slack timestamp.
+func parseDateParam(v string) (float64, error) {
+	if len(v) == 0 {
+		return 0, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return float64(t.Unix()), nil
+	}
+	ts, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %s, must be YYYY-MM-DD or a timestamp", v)
+	}
+	return ts, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// quoteState tracks quotes and brackets while scanning a command.
+type quoteState struct {
+	quote rune
+	depth int
+	prev  rune
+}
+
+func (q *quoteState) next(r rune) {
+	switch {
+	case q.quote != 0:
+		if r == q.quote {
+			q.quote = 0
+		}
+	case q.isOpener(r):
+		q.quote = r
+	case r == '{' || r == '[':
+		q.depth++
+	case (r == '}' || r == ']') && q.depth > 0:
+		q.depth--
+	}
+	q.prev = r
+}
+
+// isOpener reports whether r opens a quote: a quote at the start of a value,
+// so text=don't has none, or a JSON string inside brackets.
+func (q *quoteState) isOpener(r rune) bool {
+	if q.depth > 0 {
+		return r == '"'
+	}
+	if r != '"' && r != '\'' {
+		return false
+	}
+	return q.prev == 0 || q.prev == '=' || unicode.IsSpace(q.prev)
+}
+
+func (q *quoteState) inside() bool {
+	return q.quote != 0 || q.depth > 0
+}
+
+// isIncomplete reports whether cmd has an unclosed quote or bracket,
+// so more lines are needed.
+func isIncomplete(cmd string) bool {
+	var q quoteState
+	for _, r := range cmd {
+		q.next(r)
+	}
+	return q.inside()
+}
+
+// splitCommand splits cmd by spaces outside of quotes and brackets,
+// so text="a b" and attachments=[{"a": "b"}] are kept in one piece.
+func splitCommand(cmd string) []string {
+	var args []string
+	var q quoteState
+
+	start := -1
+	for i, r := range cmd {
+		if !q.inside() && unicode.IsSpace(r) {
+			if start >= 0 {
+				args = append(args, cmd[start:i])
+				start = -1
+			}
+			q.next(r)
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+		q.next(r)
+	}
+	if start >= 0 {
+		args = append(args, cmd[start:])
+	}
+	return args
+}
+
+func continuationPrompt(prompt string) string {
+	return strings.Repeat(".", len([]rune(prompt))-1) + ">"
+}
+
+// readCommand reads a command, going on with a continuation prompt
+// while quotes or brackets are unbalanced.
+func readCommand(prompt string) (string, error) {
+	cmd, err := line(prompt)
+	for err == nil && isIncomplete(cmd) {
+		var more string
+		more, err = line(continuationPrompt(prompt))
+		cmd += "\n" + more
+	}
+	return cmd, err
+}
+
+// previousCommand returns the newest history entry which is not an :edit.
+func previousCommand() string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(strings.ToLower(history[i]), ":edit") {
+			return history[i]
+		}
+	}
+	return ""
+}
+
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) > 0 {
+			return args
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editInEditor opens $EDITOR on text and returns what was saved.
+func editInEditor(text string, suffix string) (string, error) {
+	f, err := ioutil.TempFile("", "slack-cli-*"+suffix)
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	_, err = f.WriteString(text)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	args := editorCommand()
+	cmd := exec.Command(args[0], append(args[1:], name)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("run editor %s: %s", args[0], err.Error())
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
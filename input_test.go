@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		cmd        string
+		args       []string
+		incomplete bool
+	}{
+		{`chat.postMessage channel=C1 text=don't`, []string{"chat.postMessage", "channel=C1", "text=don't"}, false},
+		{`chat.postMessage text="it's done" channel=C1`, []string{"chat.postMessage", `text="it's done"`, "channel=C1"}, false},
+		{`chat.postMessage text='say "hi"'`, []string{"chat.postMessage", `text='say "hi"'`}, false},
+		{`status :lunch: "at lunch" 1h`, []string{"status", ":lunch:", `"at lunch"`, "1h"}, false},
+		{`chat.postMessage attachments=[{"text": "it's {up}"}]`, []string{"chat.postMessage", `attachments=[{"text": "it's {up}"}]`}, false},
+		{`chat.postMessage text="hello`, []string{"chat.postMessage", `text="hello`}, true},
+		{`chat.postMessage attachments=[{"text": "a"}`, []string{"chat.postMessage", `attachments=[{"text": "a"}`}, true},
+	}
+
+	for _, tt := range tests {
+		if args := splitCommand(tt.cmd); !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitCommand(%s) = %q, want %q", tt.cmd, args, tt.args)
+		}
+		if incomplete := isIncomplete(tt.cmd); incomplete != tt.incomplete {
+			t.Errorf("isIncomplete(%s) = %v, want %v", tt.cmd, incomplete, tt.incomplete)
+		}
+	}
+}
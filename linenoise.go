@@ -1,52 +1,129 @@
 package main
 
-//#include <stdlib.h>
-//#include "linenoise.h"
-//#include "linenoiseCompletionCallbackHook.h"
-import "C"
+// A pure Go line editor in the spirit of linenoise, so no cgo is needed.
 
 import (
+	"bufio"
 	"errors"
-	"unsafe"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
 )
 
-func init() {
-	C.linenoiseSetupCompletionCallbackHook()
+const defaultHistoryCapacity = 100
+
+// errInterrupted is returned by line when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+var unsupportedTerms = []string{"dumb", "cons25", "emacs"}
+
+var (
+	stdin  = bufio.NewReader(os.Stdin)
+	stdout = os.Stdout
+
+	history         []string
+	historyCapacity = defaultHistoryCapacity
+
+	// wrap long lines over several rows instead of scrolling horizontally
+	multiLine bool
+)
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlT     = 20
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyBackspace = 127
+)
+
+func isUnsupportedTerm() bool {
+	term := os.Getenv("TERM")
+	for _, t := range unsupportedTerms {
+		if strings.EqualFold(term, t) {
+			return true
+		}
+	}
+	return false
 }
 
 func line(prompt string) (string, error) {
-	promptCString := C.CString(prompt)
-	resultCString := C.linenoise(promptCString)
-	C.free(unsafe.Pointer(promptCString))
-	defer C.free(unsafe.Pointer(resultCString))
+	if !isTerminal(os.Stdin) || isUnsupportedTerm() {
+		return lineNoTTY(prompt)
+	}
 
-	if resultCString == nil {
-		return "", errors.New("exiting due to signal")
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return lineNoTTY(prompt)
 	}
+	defer restore()
+
+	e := &editor{prompt: []rune(prompt)}
+	result, err := e.edit()
+	fmt.Fprint(stdout, "\r\n")
+	return result, err
+}
 
-	result := C.GoString(resultCString)
+// lineNoTTY reads a plain line when stdin is not a terminal, like a pipe.
+func lineNoTTY(prompt string) (string, error) {
+	fmt.Fprint(stdout, prompt)
 
-	return result, nil
+	s, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || len(s) == 0) {
+		return "", errors.New("exiting due to end of input")
+	}
+	return strings.TrimRight(s, "\r\n"), nil
 }
 
 func addHistory(line string) error {
-	lineCString := C.CString(line)
-	res := C.linenoiseHistoryAdd(lineCString)
-	C.free(unsafe.Pointer(lineCString))
-	if res != 1 {
+	if historyCapacity <= 0 {
 		return errors.New("Could not add line to history.")
 	}
+
+	// don't add duplicated lines
+	if len(history) > 0 && history[len(history)-1] == line {
+		return nil
+	}
+
+	history = append(history, line)
+	if len(history) > historyCapacity {
+		history = history[len(history)-historyCapacity:]
+	}
 	return nil
 }
 
 func setHistoryCapacity(capacity int) error {
-	res := C.linenoiseHistorySetMaxLen(C.int(capacity))
-	if res != 1 {
+	if capacity < 1 {
 		return errors.New("Could not set history max len.")
 	}
+
+	historyCapacity = capacity
+	if len(history) > capacity {
+		history = history[len(history)-capacity:]
+	}
 	return nil
 }
 
+// setMultiLine switches between single line and multi line editing,
+// like linenoiseSetMultiLine.
+func setMultiLine(ml bool) {
+	multiLine = ml
+}
+
 // CompletionHandler provides possible completions for given input
 type CompletionHandler func(input string) []string
 
@@ -62,28 +139,391 @@ func SetCompletionHandler(c CompletionHandler) {
 	complHandler = c
 }
 
-// typedef struct linenoiseCompletions {
-//   size_t len;
-//   char **cvec;
-// } linenoiseCompletions;
-// typedef void(linenoiseCompletionCallback)(const char *, linenoiseCompletions *);
-// void linenoiseSetCompletionCallback(linenoiseCompletionCallback *);
-// void linenoiseAddCompletion(linenoiseCompletions *, char *);
+// editor is the state of the line being edited, positions are in runes.
+type editor struct {
+	prompt []rune
+	buf    []rune
+	pos    int
+
+	// the history entry being edited, counted from the newest one,
+	// the newest entry is the line being edited
+	historyIndex int
+	history      []string
+
+	// cursor column of the previous refresh and rows used so far, in multi line mode
+	oldPos  int
+	maxRows int
+}
+
+func (e *editor) edit() (string, error) {
+	// work on a copy of the history, the last entry is the current line
+	e.history = append(append([]string{}, history...), "")
+	e.refresh()
+
+	for {
+		r, _, err := stdin.ReadRune()
+		if err != nil {
+			return "", errors.New("exiting due to end of input")
+		}
+
+		if r == keyTab {
+			if r, err = e.complete(); err != nil {
+				return "", err
+			}
+			if r == 0 {
+				continue
+			}
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			if multiLine {
+				// leave the cursor below the last row
+				e.moveTo(len(e.buf))
+			}
+			return string(e.buf), nil
+		case keyCtrlC:
+			return "", errInterrupted
+		case keyBackspace, keyCtrlH:
+			e.backspace()
+		case keyCtrlD:
+			// delete the rune under the cursor, or end of input on an empty line
+			if len(e.buf) == 0 {
+				return "", errors.New("exiting due to end of input")
+			}
+			e.delete()
+		case keyCtrlT:
+			if e.pos > 0 && e.pos < len(e.buf) {
+				e.buf[e.pos-1], e.buf[e.pos] = e.buf[e.pos], e.buf[e.pos-1]
+				if e.pos < len(e.buf)-1 {
+					e.pos++
+				}
+				e.refresh()
+			}
+		case keyCtrlB:
+			e.moveTo(e.pos - 1)
+		case keyCtrlF:
+			e.moveTo(e.pos + 1)
+		case keyCtrlP:
+			e.historyMove(1)
+		case keyCtrlN:
+			e.historyMove(-1)
+		case keyCtrlU:
+			e.buf = e.buf[:0]
+			e.pos = 0
+			e.refresh()
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+			e.refresh()
+		case keyCtrlA:
+			e.moveTo(0)
+		case keyCtrlE:
+			e.moveTo(len(e.buf))
+		case keyCtrlL:
+			fmt.Fprint(stdout, "\x1b[H\x1b[2J")
+			e.refresh()
+		case keyCtrlW:
+			e.deletePrevWord()
+		case keyEsc:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+	}
+}
+
+// escape handles the escape sequences of arrows, home, end and delete keys.
+func (e *editor) escape() {
+	r1, _, err := stdin.ReadRune()
+	if err != nil {
+		return
+	}
+	r2, _, err := stdin.ReadRune()
+	if err != nil {
+		return
+	}
+
+	switch r1 {
+	case '[':
+		if r2 >= '0' && r2 <= '9' {
+			r3, _, err := stdin.ReadRune()
+			if err != nil || r3 != '~' {
+				return
+			}
+			switch r2 {
+			case '1', '7':
+				e.moveTo(0)
+			case '3':
+				e.delete()
+			case '4', '8':
+				e.moveTo(len(e.buf))
+			}
+			return
+		}
+
+		switch r2 {
+		case 'A':
+			e.historyMove(1)
+		case 'B':
+			e.historyMove(-1)
+		case 'C':
+			e.moveTo(e.pos + 1)
+		case 'D':
+			e.moveTo(e.pos - 1)
+		case 'H':
+			e.moveTo(0)
+		case 'F':
+			e.moveTo(len(e.buf))
+		}
+	case 'O':
+		switch r2 {
+		case 'H':
+			e.moveTo(0)
+		case 'F':
+			e.moveTo(len(e.buf))
+		}
+	}
+}
+
+func (e *editor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+	e.refresh()
+}
+
+func (e *editor) backspace() {
+	if e.pos > 0 {
+		e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+		e.pos--
+		e.refresh()
+	}
+}
+
+func (e *editor) delete() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+		e.refresh()
+	}
+}
+
+func (e *editor) deletePrevWord() {
+	start := e.pos
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+	e.pos = start
+	e.refresh()
+}
+
+func (e *editor) moveTo(pos int) {
+	if pos >= 0 && pos <= len(e.buf) && pos != e.pos {
+		e.pos = pos
+		e.refresh()
+	}
+}
+
+// historyMove shows an older entry when dir is 1, a newer one when dir is -1.
+func (e *editor) historyMove(dir int) {
+	if len(e.history) <= 1 {
+		return
+	}
+
+	// save the edits of the current entry
+	e.history[len(e.history)-1-e.historyIndex] = string(e.buf)
+
+	index := e.historyIndex + dir
+	if index < 0 || index >= len(e.history) {
+		return
+	}
+	e.historyIndex = index
+
+	e.buf = []rune(e.history[len(e.history)-1-index])
+	e.pos = len(e.buf)
+	e.refresh()
+}
+
+// complete cycles through the completions of the current line with Tab,
+// Esc restores the line, any other key accepts the completion and is returned
+// to be handled as usual, 0 means nothing is left to handle.
+func (e *editor) complete() (rune, error) {
+	completions := complHandler(string(e.buf))
+	if len(completions) == 0 {
+		fmt.Fprint(stdout, "\x07")
+		return 0, nil
+	}
+
+	buf, pos := e.buf, e.pos
+	for i := 0; ; {
+		if i < len(completions) {
+			e.buf = []rune(completions[i])
+			e.pos = len(e.buf)
+		} else {
+			e.buf, e.pos = buf, pos
+		}
+		e.refresh()
+
+		r, _, err := stdin.ReadRune()
+		if err != nil {
+			return 0, errors.New("exiting due to end of input")
+		}
+
+		switch r {
+		case keyTab:
+			i = (i + 1) % (len(completions) + 1)
+			if i == len(completions) {
+				fmt.Fprint(stdout, "\x07")
+			}
+		case keyEsc:
+			e.buf, e.pos = buf, pos
+			e.refresh()
+			return 0, nil
+		default:
+			e.buf = append([]rune{}, e.buf...)
+			return r, nil
+		}
+	}
+}
+
+func (e *editor) refresh() {
+	if multiLine {
+		e.refreshMultiLine()
+	} else {
+		e.refreshSingleLine()
+	}
+}
+
+// displayRunes shows newlines of a multi line command as a symbol,
+// so they don't break the layout.
+func displayRunes(rs []rune) []rune {
+	out := make([]rune, len(rs))
+	for i, r := range rs {
+		if r == '\n' {
+			r = '↵'
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// refreshSingleLine redraws the prompt and the line, scrolling horizontally so
+// the cursor is always visible when the line is wider than the terminal.
+func (e *editor) refreshSingleLine() {
+	cols := terminalColumns()
+	plen := stringWidth(e.prompt)
+
+	buf := displayRunes(e.buf)
+	pos := e.pos
+	for len(buf) > 0 && pos > 0 && plen+stringWidth(buf[:pos]) >= cols {
+		buf = buf[1:]
+		pos--
+	}
+	end := len(buf)
+	for end > pos && plen+stringWidth(buf[:end]) > cols {
+		end--
+	}
 
-//export linenoiseGoCompletionCallbackHook
-func linenoiseGoCompletionCallbackHook(input *C.char, completions *C.linenoiseCompletions) {
-	completionsSlice := complHandler(C.GoString(input))
+	var sb strings.Builder
+	sb.WriteString("\r")
+	sb.WriteString(string(e.prompt))
+	sb.WriteString(string(buf[:end]))
+	sb.WriteString("\x1b[0K")
+	sb.WriteString("\r")
+	if col := plen + stringWidth(buf[:pos]); col > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dC", col)
+	}
+	fmt.Fprint(stdout, sb.String())
+}
 
-	completionsLen := len(completionsSlice)
-	completions.len = C.size_t(completionsLen)
+// refreshMultiLine redraws the prompt and the line wrapped over as many rows as
+// needed, clearing the rows used by the previous refresh first.
+func (e *editor) refreshMultiLine() {
+	cols := terminalColumns()
+	plen := stringWidth(e.prompt)
+	buf := displayRunes(e.buf)
 
-	if completionsLen > 0 {
-		cvec := C.malloc(C.size_t(int(unsafe.Sizeof(*(**C.char)(nil))) * completionsLen))
-		cvecSlice := (*(*[999999]*C.char)(cvec))[:completionsLen]
+	rows := (plen + stringWidth(buf) + cols - 1) / cols
+	if rows == 0 {
+		rows = 1
+	}
+	// cursor row of the previous refresh, counted from 1
+	rpos := (e.oldPos + cols) / cols
+	oldRows := e.maxRows
+	if rows > e.maxRows {
+		e.maxRows = rows
+	}
 
-		for i, str := range completionsSlice {
-			cvecSlice[i] = C.CString(str)
+	var sb strings.Builder
+
+	// go to the last row, then clear every row going up
+	if oldRows-rpos > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dB", oldRows-rpos)
+	}
+	for j := 0; j < oldRows-1; j++ {
+		sb.WriteString("\r\x1b[0K\x1b[1A")
+	}
+	sb.WriteString("\r\x1b[0K")
+
+	sb.WriteString(string(e.prompt))
+	sb.WriteString(string(buf))
+
+	pos := plen + stringWidth(buf[:e.pos])
+
+	// the cursor is at the end of the last column, start a new row for it
+	if e.pos == len(buf) && pos > 0 && pos%cols == 0 {
+		sb.WriteString("\n\r")
+		rows++
+		if rows > e.maxRows {
+			e.maxRows = rows
 		}
-		completions.cvec = (**C.char)(cvec)
 	}
+
+	// move the cursor up to its row and column
+	if rows-(pos+cols)/cols > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dA", rows-(pos+cols)/cols)
+	}
+	sb.WriteString("\r")
+	if col := pos % cols; col > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dC", col)
+	}
+
+	e.oldPos = pos
+	fmt.Fprint(stdout, sb.String())
+}
+
+func stringWidth(rs []rune) int {
+	w := 0
+	for _, r := range rs {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth returns the number of columns r takes in the terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r):
+		return 0
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // CJK ... Yi
+		r >= 0xac00 && r <= 0xd7a3,                // Hangul Syllables
+		r >= 0xf900 && r <= 0xfaff,                // CJK Compatibility Ideographs
+		r >= 0xfe30 && r <= 0xfe4f,                // CJK Compatibility Forms
+		r >= 0xff00 && r <= 0xff60,                // Fullwidth Forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
 }
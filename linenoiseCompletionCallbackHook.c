@@ -1,8 +0,0 @@
-
-#include "linenoiseCompletionCallbackHook.h"
-
-extern void linenoiseGoCompletionCallbackHook(const char *, linenoiseCompletions *);
-
-void linenoiseSetupCompletionCallbackHook() {
-	linenoiseSetCompletionCallback(linenoiseGoCompletionCallbackHook);
-}
\ No newline at end of file
@@ -1,5 +0,0 @@
-
-#include <stdlib.h>
-#include "linenoise.h"
-
-void linenoiseSetupCompletionCallbackHook();
\ No newline at end of file
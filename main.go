@@ -1,72 +1,300 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/nlopes/slack"
-	"regexp"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 )
 
 var token = flag.String("token", "", "Slack Token")
+var apiURL = flag.String("api-url", "", "Alternate Web API endpoint, like http://127.0.0.1:8080/api/")
+var record = flag.String("record", "", "Record every API request and response to file, token is scrubbed")
+var replay = flag.String("replay", "", "Answer API requests from a file written by -record, without network")
+var dataDir = flag.String("data", filepath.Join(os.Getenv("HOME"), ".slack-cli"), "Directory for local data")
+var noIndex = flag.Bool("no-index", false, "Don't index fetched history for local.search")
+var verbose = flag.Bool("verbose", false, "Report throttling and retries")
+var maxRetries = flag.Int("max-retries", 3, "Max retries for rate limited calls which were refused or are idempotent")
+var timeout = flag.Duration("timeout", 0, "Timeout for each command, 0 means no timeout")
+var yes = flag.Bool("yes", false, "Run destructive commands without confirmation")
+var dryRun = flag.Bool("dry-run", false, "Print every API request as a curl command, requests which change the workspace are not sent")
+var multiLineMode = flag.Bool("multiline", false, "Wrap long input over several rows instead of scrolling")
+var readOnly = flag.Bool("read-only", os.Getenv("SLACK_CLI_READ_ONLY") == "1", "Refuse commands that change the workspace, default is on if SLACK_CLI_READ_ONLY=1, always on if read_only is set in config.json of the data directory")
 
 type Slack struct {
-	s *slack.Slack
+	s slackClient
+
+	index    *localIndex
+	auditLog *auditLog
+	drafts   *draftStore
+
+	confirmDestructive bool
+	dryRun             bool
+	readOnly           bool
+
+	undoOps []*undoOp
+	undoing bool
+
+	// the rotation shown by confirm, applied by the same command line
+	rotationPlan *rotationPlan
+
+	// emoji names for completion, loaded on first use
+	emoji []string
 }
 
 func main() {
 	flag.Parse()
 
+	cfg, err := loadConfig(filepath.Join(*dataDir, "config.json"))
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+
+	rt, err := newTransport()
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+
 	s := &Slack{}
-	s.s = slack.New(*token)
+	s.s = newAPIClient(*token, rt)
+	s.confirmDestructive = !*yes
+	s.dryRun = *dryRun
+	s.readOnly = *readOnly || cfg.ReadOnly
 
-	SetCompletionHandler(completionHandler)
-	setHistoryCapacity(100)
+	s.auditLog = newAuditLog(filepath.Join(*dataDir, "audit.jsonl"))
+	s.drafts = newDraftStore(filepath.Join(*dataDir, "drafts.json"))
 
-	reg, _ := regexp.Compile(`([^\s]*"[^"]+"[^\s]*)|([^\s]*'[^']+'[^\s]*)|[^"]?\S+[^"]?|[^']?\S+[^']?`)
+	if !*noIndex {
+		index, err := openIndex(filepath.Join(*dataDir, "index.json"))
+		if err != nil {
+			fmt.Printf("%s\n", err.Error())
+			return
+		}
+		s.index = index
+
+		defer func() {
+			if err := index.flush(); err != nil {
+				fmt.Printf("save index err: %s\n", err.Error())
+			}
+		}()
+	}
+
+	SetCompletionHandler(s.complete)
+	setHistoryCapacity(100)
+	setMultiLine(*multiLineMode)
 
 	prompt := "slack>"
+	if s.readOnly {
+		prompt = "slack(read-only)>"
+	}
 
 	for {
 
-		cmd, err := line(prompt)
-		if err != nil {
+		cmd, err := readCommand(prompt)
+		if err == errInterrupted {
+			continue
+		} else if err != nil {
 			fmt.Printf("%s\n", err.Error())
 			return
 		}
 
-		cmds := reg.FindAllString(cmd, -1)
-		for i, _ := range cmds {
-			cmds[i] = strings.TrimSpace(cmds[i])
+		if len(strings.TrimSpace(cmd)) == 0 {
+			continue
 		}
 
-		if len(cmds) == 0 {
-			continue
+		addHistory(cmd)
+		s.execute(cmd)
+	}
+}
+
+// execute runs one line of input.
+func (s *Slack) execute(line string) {
+	cmds := splitCommand(line)
+	if len(cmds) == 0 {
+		return
+	}
+
+	args := cmds[1:]
+	s.rotationPlan = nil
+
+	cmd := strings.ToLower(cmds[0])
+	if cmd == "help" || cmd == "?" {
+		printHelp(cmds)
+	} else if cmd == ":edit" {
+		s.edit(args)
+	} else if strings.HasPrefix(cmd, ":") {
+		if err := s.handleMeta(cmd, args); err != nil {
+			fmt.Printf("err: %s\n", err.Error())
 		} else {
-			addHistory(cmd)
-
-			args := cmds[1:]
-
-			cmd := strings.ToLower(cmds[0])
-			if cmd == "help" || cmd == "?" {
-				printHelp(cmds)
-			} else {
-				v, err := s.handle(cmd, args)
-				if err != nil {
-					fmt.Printf("err: %s", err.Error())
-				} else if v != nil {
-					buf, _ := json.MarshalIndent(v, "", "    ")
-					fmt.Printf("%s", buf)
-				} else {
-					fmt.Printf("ok")
-				}
-
-				fmt.Printf("\n")
-			}
+			fmt.Printf("ok\n")
+		}
+	} else if cmd == "chat.compose" {
+		s.compose(args)
+	} else if cmd == "status" {
+		printResult(s.runParams("users.profile.set", statusParams(args)))
+	} else if cmd == "undo" {
+		printResult(s.undo())
+	} else if !s.confirm(cmd, args) {
+		fmt.Printf("canceled\n")
+	} else {
+		printResult(s.run(cmd, args))
+	}
+}
+
+// edit opens the given command, or the previous one, in $EDITOR
+// and runs what was saved.
+func (s *Slack) edit(args []string) {
+	text := strings.Join(args, " ")
+	if len(args) == 0 {
+		text = previousCommand()
+	}
+
+	edited, err := editInEditor(text, ".txt")
+	if err != nil {
+		fmt.Printf("err: %s\n", err.Error())
+		return
+	}
+	if len(strings.TrimSpace(edited)) == 0 {
+		fmt.Printf("canceled\n")
+		return
+	}
 
+	fmt.Println(edited)
+	addHistory(edited)
+	s.execute(edited)
+}
+
+// newTransport builds the layers under the slack client, from the top:
+// base url rewrite, dry run, rate limit, then record, replay or network.
+func newTransport() (http.RoundTripper, error) {
+	var rt http.RoundTripper = http.DefaultTransport
+	var err error
+
+	if len(*replay) > 0 && len(*record) > 0 {
+		return nil, fmt.Errorf("-record and -replay can't be used together")
+	} else if len(*replay) > 0 {
+		if rt, err = newReplayTransport(*replay); err != nil {
+			return nil, err
+		}
+	} else if len(*record) > 0 {
+		if rt, err = newRecordTransport(rt, *record); err != nil {
+			return nil, err
+		}
+	}
+
+	rt = newDryRunTransport(newRateLimitTransport(rt, *maxRetries, *verbose))
+
+	if len(*apiURL) > 0 {
+		if rt, err = newBaseURLTransport(rt, *apiURL); err != nil {
+			return nil, err
+		}
+	}
+	return rt, nil
+}
+
+// run handles one command, SIGINT or the timeout cancels it but keeps the session alive.
+func (s *Slack) run(cmd string, args []string) (interface{}, error) {
+	return s.runParams(cmd, extractParams(args))
+}
+
+// runParams is run with params which are parsed already.
+func (s *Slack) runParams(cmd string, params map[string]string) (interface{}, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, *timeout)
+		defer cancelTimeout()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return s.handleParams(ctx, cmd, params)
+}
+
+// table is a result shown as aligned columns instead of JSON.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func printResult(v interface{}, err error) {
+	if err != nil {
+		fmt.Printf("err: %s", err.Error())
+	} else if t, ok := v.(*table); ok {
+		fmt.Printf("%s", t)
+	} else if v != nil {
+		buf, _ := json.MarshalIndent(v, "", "    ")
+		fmt.Printf("%s", buf)
+	} else {
+		fmt.Printf("ok")
+	}
+
+	fmt.Printf("\n")
+}
+
+// handleMeta changes session settings, like ":confirm off".
+func (s *Slack) handleMeta(cmd string, args []string) error {
+	switch cmd {
+	case ":confirm":
+		on, err := parseSwitch(cmd, args)
+		if err != nil {
+			return err
+		}
+		s.confirmDestructive = on
+	case ":multiline":
+		on, err := parseSwitch(cmd, args)
+		if err != nil {
+			return err
+		}
+		setMultiLine(on)
+	default:
+		return fmt.Errorf("unknown setting %s", cmd)
+	}
+	return nil
+}
+
+func parseSwitch(cmd string, args []string) (bool, error) {
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			return true, nil
+		case "off":
+			return false, nil
 		}
 	}
+	return false, fmt.Errorf("usage: %s on|off", cmd)
 }
 
 func printGenericHelp() {
@@ -100,6 +328,14 @@ func printHelp(cmds []string) {
 	}
 }
 
+// complete completes emoji names of reactions commands, or command names.
+func (s *Slack) complete(in string) []string {
+	if lines := s.completeEmoji(in); len(lines) > 0 {
+		return lines
+	}
+	return completionHandler(in)
+}
+
 func completionHandler(in string) []string {
 	var keyWords []string
 	for _, i := range helpCommands {
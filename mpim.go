@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+// MpIM is a multi-person IM, a private group of up to 8 people without a name of its own.
+type MpIM struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Creator string   `json:"creator"`
+	IsMpIM  bool     `json:"is_mpim"`
+	IsOpen  bool     `json:"is_open"`
+	Members []string `json:"members"`
+}
+
+func (c *apiClient) CloseMpIM(ctx context.Context, channel string) error {
+	return c.call(ctx, "mpim.close", url.Values{"channel": {channel}}, nil)
+}
+
+func (c *apiClient) GetMpIMHistory(ctx context.Context, channel string, params slack.HistoryParameters) (*History, error) {
+	return c.history(ctx, "mpim.history", channel, params)
+}
+
+func (c *apiClient) GetMpIMs(ctx context.Context) ([]MpIM, error) {
+	var r struct {
+		Groups []MpIM `json:"groups"`
+	}
+	if err := c.call(ctx, "mpim.list", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Groups, nil
+}
+
+func (c *apiClient) MarkMpIM(ctx context.Context, channel string, ts string) error {
+	return c.call(ctx, "mpim.mark", url.Values{"channel": {channel}, "ts": {ts}}, nil)
+}
+
+// OpenMpIM opens the multi-person IM of users, the token user is always a member.
+func (c *apiClient) OpenMpIM(ctx context.Context, users []string) (*MpIM, error) {
+	var r struct {
+		Group *MpIM `json:"group"`
+	}
+	if err := c.call(ctx, "mpim.open", url.Values{"users": {strings.Join(users, ",")}}, &r); err != nil {
+		return nil, err
+	}
+	return r.Group, nil
+}
+
+func (s *Slack) handleMpIM(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "close":
+		err = s.s.CloseMpIM(ctx, params["channel"])
+	case "history":
+		historyParms := slack.HistoryParameters{}
+		historyParms.Latest = getStringParam(params, "latest", slack.DEFAULT_HISTORY_LATEST)
+
+		historyParms.Oldest = getStringParam(params, "oldest", slack.DEFAULT_HISTORY_OLDEST)
+
+		historyParms.Count = getIntParam(params, "count", slack.DEFAULT_HISTORY_COUNT)
+
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		h, err := s.s.GetMpIMHistory(ctx, channel, historyParms)
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, h.Messages)
+		markThreads(channel, h.Messages)
+		v = h
+	case "list":
+		mpims, err := s.s.GetMpIMs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		v = map[string]interface{}{
+			"groups": mpims,
+		}
+	case "mark":
+		err = s.s.MarkMpIM(ctx, params["channel"], params["ts"])
+	case "open":
+		users, err := s.resolveUsers(ctx, params["users"])
+		if err != nil {
+			return nil, err
+		}
+
+		mpim, err := s.s.OpenMpIM(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+
+		v = map[string]interface{}{
+			"group": mpim,
+		}
+	default:
+		return nil, fmt.Errorf("invalid mpim action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PinnedItem is a message, file or file comment pinned to a channel.
+type PinnedItem struct {
+	Type      string       `json:"type"`
+	Channel   string       `json:"channel,omitempty"`
+	Created   int64        `json:"created,omitempty"`
+	CreatedBy string       `json:"created_by,omitempty"`
+	Message   *Message     `json:"message,omitempty"`
+	File      *ItemFile    `json:"file,omitempty"`
+	Comment   *ItemComment `json:"comment,omitempty"`
+
+	// Preview is added by pins.list, not part of the API.
+	Preview string `json:"preview,omitempty"`
+}
+
+func (c *apiClient) AddPin(ctx context.Context, channel string, item itemRef) error {
+	values := item.values()
+	values.Set("channel", channel)
+	return c.call(ctx, "pins.add", values, nil)
+}
+
+func (c *apiClient) RemovePin(ctx context.Context, channel string, item itemRef) error {
+	values := item.values()
+	values.Set("channel", channel)
+	return c.call(ctx, "pins.remove", values, nil)
+}
+
+func (c *apiClient) ListPins(ctx context.Context, channel string) ([]PinnedItem, error) {
+	var r struct {
+		Items []PinnedItem `json:"items"`
+	}
+	if err := c.call(ctx, "pins.list", url.Values{"channel": {channel}}, &r); err != nil {
+		return nil, err
+	}
+	return r.Items, nil
+}
+
+// pinPreview shows a pinned item in one line, like "@alice: deploy runbook ...".
+func (s *Slack) pinPreview(ctx context.Context, item *PinnedItem, names map[string]string) string {
+	switch {
+	case item.Message != nil:
+		user := item.Message.User
+		if name, ok := names[user]; ok {
+			user = "@" + name
+		}
+		return fmt.Sprintf("%s: %s", user, truncate(s.renderMentions(ctx, item.Message.Text, names), 80))
+	case item.File != nil:
+		return fmt.Sprintf("file %s %q", item.File.Id, item.File.Title)
+	case item.Comment != nil:
+		return fmt.Sprintf("comment: %s", truncate(item.Comment.Comment, 80))
+	}
+	return ""
+}
+
+func (s *Slack) handlePins(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "add":
+		err = s.s.AddPin(ctx, params["channel"], itemRefParams(params))
+	case "list":
+		items, err := s.s.ListPins(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+
+		// previews show ids if the users can't be listed
+		names, _ := s.userNames(ctx)
+		for i := range items {
+			items[i].Preview = s.pinPreview(ctx, &items[i], names)
+		}
+		v = map[string]interface{}{
+			"items": items,
+		}
+	case "remove":
+		err = s.s.RemovePin(ctx, params["channel"], itemRefParams(params))
+	default:
+		return nil, fmt.Errorf("invalid pins action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ProfileField struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+	Label string `json:"label,omitempty"`
+}
+
+// profileFields are the custom fields by id, the API sends an empty array
+// instead of an empty object when there are none.
+type profileFields map[string]ProfileField
+
+func (f *profileFields) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		*f = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]ProfileField)(f))
+}
+
+type UserProfile struct {
+	RealName         string        `json:"real_name"`
+	DisplayName      string        `json:"display_name"`
+	FirstName        string        `json:"first_name"`
+	LastName         string        `json:"last_name"`
+	Title            string        `json:"title"`
+	Phone            string        `json:"phone"`
+	Email            string        `json:"email"`
+	StatusText       string        `json:"status_text"`
+	StatusEmoji      string        `json:"status_emoji"`
+	StatusExpiration int64         `json:"status_expiration"`
+	Image72          string        `json:"image_72,omitempty"`
+	ImageOriginal    string        `json:"image_original,omitempty"`
+	Fields           profileFields `json:"fields"`
+}
+
+// TeamProfileField is the definition of a custom profile field.
+type TeamProfileField struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+	Hint  string `json:"hint"`
+	Type  string `json:"type"`
+}
+
+func (c *apiClient) GetUserProfile(ctx context.Context, user string, includeLabels bool) (*UserProfile, error) {
+	values := url.Values{}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+	if includeLabels {
+		values.Set("include_labels", "true")
+	}
+
+	var r struct {
+		Profile *UserProfile `json:"profile"`
+	}
+	if err := c.call(ctx, "users.profile.get", values, &r); err != nil {
+		return nil, err
+	}
+	return r.Profile, nil
+}
+
+// SetUserProfile changes the given profile keys of user, or of the token user if empty.
+func (c *apiClient) SetUserProfile(ctx context.Context, user string, profile map[string]interface{}) (*UserProfile, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{"profile": {string(data)}}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+
+	var r struct {
+		Profile *UserProfile `json:"profile"`
+	}
+	if err = c.call(ctx, "users.profile.set", values, &r); err != nil {
+		return nil, err
+	}
+	return r.Profile, nil
+}
+
+func (c *apiClient) GetTeamProfileFields(ctx context.Context) ([]TeamProfileField, error) {
+	var r struct {
+		Profile struct {
+			Fields []TeamProfileField `json:"fields"`
+		} `json:"profile"`
+	}
+	if err := c.call(ctx, "team.profile.get", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Profile.Fields, nil
+}
+
+// SetUserPhoto uploads the image at path, cropped to a square of cropW at
+// cropX and cropY if cropW is not 0.
+func (c *apiClient) SetUserPhoto(ctx context.Context, path string, cropX int, cropY int, cropW int) error {
+	values := url.Values{}
+	if cropW > 0 {
+		values.Set("crop_x", strconv.Itoa(cropX))
+		values.Set("crop_y", strconv.Itoa(cropY))
+		values.Set("crop_w", strconv.Itoa(cropW))
+	}
+	return c.upload(ctx, "users.setPhoto", values, "image", path, nil)
+}
+
+// profileKeys are the standard profile keys users.profile.set takes as params.
+var profileKeys = []string{
+	"real_name", "display_name", "first_name", "last_name", "title", "phone",
+	"status_text", "status_emoji", "status_expiration",
+}
+
+const fieldPrefix = "field."
+
+// parseExpiration accepts a duration from now like 1h, a YYYY-MM-DD date or a
+// unix time, 0 or empty means the status doesn't expire.
+func parseExpiration(v string) (int64, error) {
+	if len(v) == 0 || v == "0" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(d).Unix(), nil
+	}
+	t, err := parseDateParam(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid status_expiration %s, must be a duration like 1h, YYYY-MM-DD or a unix time", v)
+	}
+	return int64(t), nil
+}
+
+// emojiCode wraps an emoji name in colons, like :lunch:.
+func emojiCode(name string) string {
+	if len(name) == 0 {
+		return name
+	}
+	return ":" + strings.Trim(name, ":") + ":"
+}
+
+// profileFieldIDs maps the field.<id or label> params to custom field ids.
+func (s *Slack) profileFieldIDs(ctx context.Context, params map[string]string) (map[string]string, error) {
+	ids := make(map[string]string)
+	var fields []TeamProfileField
+	for key := range params {
+		if !strings.HasPrefix(key, fieldPrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(key, fieldPrefix)
+		if fields == nil {
+			var err error
+			if fields, err = s.s.GetTeamProfileFields(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		ids[key] = ""
+		for _, f := range fields {
+			if f.Id == name || strings.EqualFold(f.Label, name) {
+				ids[key] = f.Id
+			}
+		}
+		if len(ids[key]) == 0 {
+			return nil, fmt.Errorf("unknown profile field %s", name)
+		}
+	}
+	return ids, nil
+}
+
+// profileUpdate builds the profile object of users.profile.set from params.
+func (s *Slack) profileUpdate(ctx context.Context, params map[string]string) (map[string]interface{}, error) {
+	profile := make(map[string]interface{})
+	for _, key := range profileKeys {
+		value, ok := params[key]
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "status_emoji":
+			profile[key] = emojiCode(value)
+		case "status_expiration":
+			expiration, err := parseExpiration(value)
+			if err != nil {
+				return nil, err
+			}
+			profile[key] = expiration
+		default:
+			profile[key] = value
+		}
+	}
+
+	ids, err := s.profileFieldIDs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		fields := make(map[string]ProfileField)
+		for key, id := range ids {
+			fields[id] = ProfileField{Value: params[key]}
+		}
+		profile["fields"] = fields
+	}
+
+	if len(profile) == 0 {
+		return nil, fmt.Errorf("nothing to set, pass %s or field.<label>", strings.Join(profileKeys, ", "))
+	}
+	return profile, nil
+}
+
+// profileParams returns the current values of the profile params being set,
+// so users.profile.set can restore them.
+func (s *Slack) profileParams(ctx context.Context, user string, params map[string]string) (map[string]string, error) {
+	ids, err := s.profileFieldIDs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.s.GetUserProfile(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]string{
+		"real_name":         p.RealName,
+		"display_name":      p.DisplayName,
+		"first_name":        p.FirstName,
+		"last_name":         p.LastName,
+		"title":             p.Title,
+		"phone":             p.Phone,
+		"status_text":       p.StatusText,
+		"status_emoji":      p.StatusEmoji,
+		"status_expiration": strconv.FormatInt(p.StatusExpiration, 10),
+	}
+
+	old := make(map[string]string)
+	for _, key := range profileKeys {
+		if _, ok := params[key]; ok {
+			old[key] = current[key]
+		}
+	}
+	for _, id := range ids {
+		old[fieldPrefix+id] = p.Fields[id].Value
+	}
+	if len(user) > 0 {
+		old["user"] = user
+	}
+	return old, nil
+}
+
+// statusKeys are the params the status shortcut passes on, like dry_run=1,
+// any other arg is a word of the status.
+var statusKeys = map[string]bool{"dry_run": true, "user": true, "timeout": true}
+
+// unquote removes the quotes around a word, like "at lunch", and keeps the
+// ones inside, like don't.
+func unquote(word string) string {
+	if len(word) >= 2 && (word[0] == '"' || word[0] == '\'') && word[len(word)-1] == word[0] {
+		return word[1 : len(word)-1]
+	}
+	return word
+}
+
+// statusParams turns the args of the status shortcut, like :lunch: "at lunch" 1h,
+// into users.profile.set params, no args clear the status.
+func statusParams(args []string) map[string]string {
+	params := make(map[string]string)
+	var words []string
+	for _, arg := range args {
+		seps := strings.SplitN(arg, "=", 2)
+		if len(seps) == 2 && statusKeys[seps[0]] {
+			params[seps[0]] = unquote(seps[1])
+		} else {
+			words = append(words, unquote(arg))
+		}
+	}
+
+	var emoji, text, expiration string
+	for i, arg := range words {
+		if _, err := time.ParseDuration(arg); err == nil && i == len(words)-1 {
+			expiration = arg
+		} else if len(emoji) == 0 && len(text) == 0 && strings.HasPrefix(arg, ":") && strings.HasSuffix(arg, ":") && len(arg) > 1 {
+			emoji = arg
+		} else if len(text) > 0 {
+			text += " " + arg
+		} else {
+			text = arg
+		}
+	}
+
+	if len(expiration) == 0 {
+		expiration = "0"
+	}
+	params["status_emoji"] = emoji
+	params["status_text"] = text
+	params["status_expiration"] = expiration
+	return params
+}
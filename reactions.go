@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+type Reaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// ReactedItem is a message, file or file comment with its reactions.
+type ReactedItem struct {
+	Type    string       `json:"type"`
+	Channel string       `json:"channel,omitempty"`
+	Message *Message     `json:"message,omitempty"`
+	File    *ItemFile    `json:"file,omitempty"`
+	Comment *ItemComment `json:"comment,omitempty"`
+}
+
+func (c *apiClient) AddReaction(ctx context.Context, name string, item itemRef) error {
+	values := item.values()
+	values.Set("name", name)
+	return c.call(ctx, "reactions.add", values, nil)
+}
+
+func (c *apiClient) RemoveReaction(ctx context.Context, name string, item itemRef) error {
+	values := item.values()
+	values.Set("name", name)
+	return c.call(ctx, "reactions.remove", values, nil)
+}
+
+func (c *apiClient) GetReactions(ctx context.Context, item itemRef, full bool) (*ReactedItem, error) {
+	values := item.values()
+	if full {
+		values.Set("full", "true")
+	}
+
+	r := new(ReactedItem)
+	if err := c.call(ctx, "reactions.get", values, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *apiClient) ListReactions(ctx context.Context, user string, count int, page int, full bool) ([]ReactedItem, *slack.Paging, error) {
+	values := url.Values{
+		"count": {strconv.Itoa(count)},
+		"page":  {strconv.Itoa(page)},
+	}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+	if full {
+		values.Set("full", "true")
+	}
+
+	var r struct {
+		Items  []ReactedItem `json:"items"`
+		Paging slack.Paging  `json:"paging"`
+	}
+	if err := c.call(ctx, "reactions.list", values, &r); err != nil {
+		return nil, nil, err
+	}
+	return r.Items, &r.Paging, nil
+}
+
+// reactionName accepts :name: like slack shows it.
+func reactionName(params map[string]string) string {
+	return strings.Trim(params["name"], ":")
+}
+
+func (s *Slack) handleReactions(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "add":
+		err = s.s.AddReaction(ctx, reactionName(params), itemRefParams(params))
+	case "get":
+		v, err = s.s.GetReactions(ctx, itemRefParams(params), getBoolParam(params, "full", false))
+	case "list":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		items, paging, err := s.s.ListReactions(ctx, user,
+			getIntParam(params, "count", 100), getIntParam(params, "page", 1), getBoolParam(params, "full", false))
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"items":  items,
+			"paging": paging,
+		}
+	case "remove":
+		err = s.s.RemoveReaction(ctx, reactionName(params), itemRefParams(params))
+	default:
+		return nil, fmt.Errorf("invalid reactions action %s", action)
+	}
+
+	return v, err
+}
+
+// standard emoji slack always has, emoji.list only returns the custom ones
+var standardEmoji = []string{
+	"+1", "-1", "100", "clap", "eyes", "fire", "heart", "heavy_check_mark",
+	"joy", "ok_hand", "pray", "raised_hands", "rocket", "see_no_evil", "smile",
+	"tada", "thinking_face", "warning", "wave", "white_check_mark", "x",
+}
+
+// emojiParam matches a reactions command being typed up to its name=
+var emojiParam = regexp.MustCompile(`(?i)^(reactions\.\S+\s.*\bname=:?)([^\s:]*)$`)
+
+// emojiNames returns the standard and custom emoji names, the custom ones
+// are fetched with emoji.list once.
+func (s *Slack) emojiNames(ctx context.Context) []string {
+	if s.emoji == nil {
+		s.emoji = append([]string{}, standardEmoji...)
+		if custom, err := s.s.GetEmoji(ctx); err == nil {
+			for name := range custom {
+				s.emoji = append(s.emoji, name)
+			}
+		}
+		sort.Strings(s.emoji)
+	}
+	return s.emoji
+}
+
+// completeEmoji completes the name of reactions commands.
+func (s *Slack) completeEmoji(in string) []string {
+	m := emojiParam.FindStringSubmatch(in)
+	if m == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, name := range s.emojiNames(context.Background()) {
+		if strings.HasPrefix(name, m[2]) {
+			lines = append(lines, m[1]+name)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Reminder struct {
+	Id         string `json:"id"`
+	Creator    string `json:"creator"`
+	User       string `json:"user"`
+	Text       string `json:"text"`
+	Recurring  bool   `json:"recurring"`
+	Time       int64  `json:"time,omitempty"`
+	CompleteTs int64  `json:"complete_ts,omitempty"`
+}
+
+// AddReminder adds a reminder for user, or for the token user if empty, at
+// when, a unix time or a text slack parses, like "every weekday at 10:00".
+func (c *apiClient) AddReminder(ctx context.Context, text string, when string, user string) (*Reminder, error) {
+	values := url.Values{"text": {text}, "time": {when}}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+
+	var r struct {
+		Reminder *Reminder `json:"reminder"`
+	}
+	if err := c.call(ctx, "reminders.add", values, &r); err != nil {
+		return nil, err
+	}
+	return r.Reminder, nil
+}
+
+func (c *apiClient) CompleteReminder(ctx context.Context, id string) error {
+	return c.call(ctx, "reminders.complete", url.Values{"reminder": {id}}, nil)
+}
+
+func (c *apiClient) DeleteReminder(ctx context.Context, id string) error {
+	return c.call(ctx, "reminders.delete", url.Values{"reminder": {id}}, nil)
+}
+
+func (c *apiClient) GetReminder(ctx context.Context, id string) (*Reminder, error) {
+	var r struct {
+		Reminder *Reminder `json:"reminder"`
+	}
+	if err := c.call(ctx, "reminders.info", url.Values{"reminder": {id}}, &r); err != nil {
+		return nil, err
+	}
+	return r.Reminder, nil
+}
+
+func (c *apiClient) ListReminders(ctx context.Context) ([]Reminder, error) {
+	var r struct {
+		Reminders []Reminder `json:"reminders"`
+	}
+	if err := c.call(ctx, "reminders.list", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Reminders, nil
+}
+
+var (
+	// in 20 minutes, in 2h, in 1 day
+	relativeTime = regexp.MustCompile(`^in\s+(\d+)\s*(m|mins?|minutes?|h|hrs?|hours?|d|days?|w|weeks?)$`)
+	// tomorrow 9am, friday at 17:30, 2026-05-01 10:00, at 9:30 pm
+	dayTime = regexp.MustCompile(`^(?:(today|tomorrow|monday|tuesday|wednesday|thursday|friday|saturday|sunday|\d{4}-\d{2}-\d{2})\s*)?(?:(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?)?$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// parseReminderTime turns a time expression into a unix time in local time.
+// Expressions it doesn't know, like recurring "every weekday at 10:00", are
+// returned as they are, for slack to parse. A time today which is past fails
+// rather than reminding right away.
+func parseReminderTime(expr string, now time.Time) (string, error) {
+	expr = strings.ToLower(strings.Join(strings.Fields(expr), " "))
+
+	if n, err := strconv.Atoi(expr); err == nil {
+		switch {
+		case len(expr) >= 10:
+			// a unix time
+			return expr, nil
+		case len(expr) == 3 || len(expr) == 4:
+			// an hour and minutes like 1730
+			expr = fmt.Sprintf("%d:%02d", n/100, n%100)
+		case len(expr) > 4:
+			return "", fmt.Errorf("invalid time %s, a unix time has 10 digits", expr)
+		}
+	}
+	if d, err := time.ParseDuration(expr); err == nil {
+		return strconv.FormatInt(now.Add(d).Unix(), 10), nil
+	}
+
+	if m := relativeTime.FindStringSubmatch(expr); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		// days and weeks keep the time of day over a DST change
+		switch m[2][0] {
+		case 'm':
+			return strconv.FormatInt(now.Add(time.Duration(n)*time.Minute).Unix(), 10), nil
+		case 'h':
+			return strconv.FormatInt(now.Add(time.Duration(n)*time.Hour).Unix(), 10), nil
+		case 'd':
+			return strconv.FormatInt(now.AddDate(0, 0, n).Unix(), 10), nil
+		default:
+			return strconv.FormatInt(now.AddDate(0, 0, 7*n).Unix(), 10), nil
+		}
+	}
+
+	m := dayTime.FindStringSubmatch(expr)
+	if m == nil || len(expr) == 0 {
+		return expr, nil
+	}
+
+	// a day without a time is at 9am
+	hour, minute := 9, 0
+	if len(m[2]) > 0 {
+		hour, _ = strconv.Atoi(m[2])
+		minute, _ = strconv.Atoi(m[3])
+		switch {
+		case m[4] == "pm" && hour < 12:
+			hour += 12
+		case m[4] == "am" && hour == 12:
+			hour = 0
+		}
+		if hour > 23 || minute > 59 {
+			return "", fmt.Errorf("invalid time %s", expr)
+		}
+	}
+
+	// built from the wall clock, adding hours to midnight is an hour off on
+	// the days DST changes
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	switch {
+	case m[1] == "tomorrow":
+		t = t.AddDate(0, 0, 1)
+	case len(m[1]) == 0:
+		// a bare time is the next one
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+	case m[1] == "today":
+		if !t.After(now) {
+			return "", fmt.Errorf("%s is in the past", expr)
+		}
+	default:
+		if wd, ok := weekdays[m[1]]; ok {
+			n := (int(wd) - int(now.Weekday()) + 7) % 7
+			if t = t.AddDate(0, 0, n); !t.After(now) {
+				t = t.AddDate(0, 0, 7)
+			}
+		} else {
+			d, err := time.ParseInLocation("2006-01-02", m[1], now.Location())
+			if err != nil {
+				return "", fmt.Errorf("invalid date %s", m[1])
+			}
+			t = time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, now.Location())
+		}
+	}
+	return strconv.FormatInt(t.Unix(), 10), nil
+}
+
+// reminderTable shows reminders one per row with due times in local time.
+func reminderTable(reminders []Reminder) *table {
+	t := &table{header: []string{"ID", "DUE", "USER", "DONE", "TEXT"}}
+	for _, r := range reminders {
+		due := "recurring"
+		if !r.Recurring {
+			due = time.Unix(r.Time, 0).Format("2006-01-02 15:04 Mon")
+		}
+		done := ""
+		if r.CompleteTs > 0 {
+			done = "yes"
+		}
+		t.rows = append(t.rows, []string{r.Id, due, r.User, done, truncate(r.Text, 50)})
+	}
+	return t
+}
+
+func (s *Slack) handleReminders(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "add":
+		if len(params["text"]) == 0 || len(params["time"]) == 0 {
+			return nil, fmt.Errorf("text and time are required")
+		}
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		when, err := parseReminderTime(params["time"], time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := s.s.AddReminder(ctx, params["text"], when, user)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"reminder": r,
+		}
+	case "complete":
+		err = s.s.CompleteReminder(ctx, params["reminder"])
+	case "delete":
+		err = s.s.DeleteReminder(ctx, params["reminder"])
+	case "info":
+		r, err := s.s.GetReminder(ctx, params["reminder"])
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"reminder": r,
+		}
+	case "list":
+		reminders, err := s.s.ListReminders(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if getBoolParam(params, "table", false) {
+			return reminderTable(reminders), nil
+		}
+		v = map[string]interface{}{
+			"reminders": reminders,
+		}
+	default:
+		return nil, fmt.Errorf("invalid reminders action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	// a Monday
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	at := func(day int, hour int, minute int) string {
+		return strconv.FormatInt(time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC).Unix(), 10)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+		err  bool
+	}{
+		{"1792400000", "1792400000", false},
+		{"1730", at(19, 17, 30), false},
+		{"930", at(20, 9, 30), false},
+		{"12345", "", true},
+		{"9", at(20, 9, 0), false},
+		{"17:30", at(19, 17, 30), false},
+		{"at 9:30 pm", at(19, 21, 30), false},
+		{"in 20 minutes", at(19, 10, 20), false},
+		{"2h", at(19, 12, 0), false},
+		{"today 5pm", at(19, 17, 0), false},
+		{"today 9am", "", true},
+		{"today", "", true},
+		{"tomorrow", at(20, 9, 0), false},
+		{"tomorrow 9am", at(20, 9, 0), false},
+		{"friday at 17:30", at(23, 17, 30), false},
+		{"monday 11am", at(19, 11, 0), false},
+		{"monday 9am", at(26, 9, 0), false},
+		{"2026-11-01 10:00", strconv.FormatInt(time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC).Unix(), 10), false},
+		{"25:00", "", true},
+		{"every weekday at 10:00", "every weekday at 10:00", false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReminderTime(tt.expr, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseReminderTime(%s) = %s, want an error", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseReminderTime(%s): %v", tt.expr, err)
+		} else if got != tt.want {
+			t.Errorf("parseReminderTime(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseReminderTimeDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// the Saturday before DST ends on Sunday 2026-11-01
+	now := time.Date(2026, 10, 31, 10, 0, 0, 0, loc)
+	at := func(month time.Month, day int, hour int, minute int) string {
+		return strconv.FormatInt(time.Date(2026, month, day, hour, minute, 0, 0, loc).Unix(), 10)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"today 5pm", at(10, 31, 17, 0)},
+		{"tomorrow 9am", at(11, 1, 9, 0)},
+		{"930", at(11, 1, 9, 30)},
+		{"monday 9am", at(11, 2, 9, 0)},
+		{"2026-11-01 10:00", at(11, 1, 10, 0)},
+		{"in 2 days", at(11, 2, 10, 0)},
+		{"in 1 week", at(11, 7, 10, 0)},
+		{"in 24 hours", strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReminderTime(tt.expr, now)
+		if err != nil {
+			t.Errorf("parseReminderTime(%s): %v", tt.expr, err)
+		} else if got != tt.want {
+			t.Errorf("parseReminderTime(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// resolveUser maps @name to a user id, anything else is returned as is.
+func (s *Slack) resolveUser(ctx context.Context, user string) (string, error) {
+	if !strings.HasPrefix(user, "@") {
+		return user, nil
+	}
+
+	name := user[1:]
+	users, err := s.s.GetUsers(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, u := range users {
+		if u.Name == name {
+			return u.Id, nil
+		}
+	}
+	return "", fmt.Errorf("user %s not found", user)
+}
+
+// resolveChannel maps #name to a channel or group id, anything else is returned as is.
+func (s *Slack) resolveChannel(ctx context.Context, channel string) (string, error) {
+	if !strings.HasPrefix(channel, "#") {
+		return channel, nil
+	}
+
+	name := channel[1:]
+	chs, err := s.s.GetChannels(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	for _, ch := range chs {
+		if ch.Name == name {
+			return ch.Id, nil
+		}
+	}
+
+	groups, err := s.s.GetGroups(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	for _, g := range groups {
+		if g.Name == name {
+			return g.Id, nil
+		}
+	}
+	return "", fmt.Errorf("channel %s not found", channel)
+}
+
+// resolveParams returns a copy of params with the #names and @names of
+// channel, user and users replaced by ids, so a command and its audit entry
+// have what it acts on even if a name changes later.
+func (s *Slack) resolveParams(ctx context.Context, params map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(params))
+	for key, value := range params {
+		resolved[key] = value
+	}
+
+	var err error
+	if channel, ok := params["channel"]; ok {
+		if resolved["channel"], err = s.resolveChannel(ctx, channel); err != nil {
+			return nil, err
+		}
+	}
+	if user, ok := params["user"]; ok {
+		if resolved["user"], err = s.resolveUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	if users := params["users"]; len(users) > 0 {
+		ids, err := s.resolveUsers(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+		resolved["users"] = strings.Join(ids, ",")
+	}
+	return resolved, nil
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nlopes/slack"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func extractParams(args []string) map[string]string {
@@ -21,8 +23,15 @@ func extractParams(args []string) map[string]string {
 	return params
 }
 
-func (s *Slack) handle(cmd string, args []string) (interface{}, error) {
-	cmds := strings.Split(cmd, ".")
+func (s *Slack) handle(ctx context.Context, cmd string, args []string) (interface{}, error) {
+	return s.handleParams(ctx, cmd, extractParams(args))
+}
+
+// handleParams runs cmd with params which are parsed already, so values like
+// a restored message text are sent as they are.
+func (s *Slack) handleParams(ctx context.Context, cmd string, params map[string]string) (interface{}, error) {
+	// the action may have dots too, like users.profile.get
+	cmds := strings.SplitN(cmd, ".", 2)
 	if len(cmds) != 2 {
 		return nil, fmt.Errorf("cmd must be type.action format, not %s", cmd)
 	}
@@ -32,39 +41,126 @@ func (s *Slack) handle(cmd string, args []string) (interface{}, error) {
 
 	tp := strings.ToLower(cmds[0])
 	action := strings.ToLower(cmds[1])
-	params := extractParams(args)
+
+	if s.readOnly && !isReadOnly(cmd) {
+		return nil, fmt.Errorf("%s is not a read-only command, not allowed in read-only mode", cmd)
+	}
+
+	if timeout, ok := params["timeout"]; ok {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %s", timeout)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	dryRun := s.isDryRun(params)
+	if dryRun {
+		ctx = withDryRun(ctx)
+	}
+
+	if isMutating(cmd) {
+		resolved, err := s.resolveParams(ctx, params)
+		if err != nil {
+			if !dryRun {
+				s.audit(cmd, params, nil, err)
+			}
+			return nil, err
+		}
+		params = resolved
+	}
+
+	var undo *undoOp
+	var undoErr error
+	if !dryRun && !s.undoing {
+		undo, undoErr = s.prepareUndo(ctx, cmd, params)
+	}
 
 	switch tp {
 	case "api":
 		err = fmt.Errorf("%s has not been supported", tp)
+	case "audit":
+		v, err = s.handleAudit(ctx, action, params)
 	case "auth":
-		v, err = s.s.AuthTest()
+		v, err = s.s.AuthTest(ctx)
 	case "channels":
-		v, err = s.handleChannels(action, params)
+		v, err = s.handleChannels(ctx, action, params)
 	case "chat":
-		v, err = s.handleChat(action, params)
+		v, err = s.handleChat(ctx, action, params)
+	case "conversations":
+		v, err = s.handleConversations(ctx, action, params)
+	case "dnd":
+		v, err = s.handleDND(ctx, action, params)
+	case "drafts":
+		v, err = s.handleDrafts(ctx, action, params)
 	case "emoji":
-		v, err = s.handleEmoji(action, params)
+		v, err = s.handleEmoji(ctx, action, params)
 	case "files":
-		v, err = s.handleFiles(action, params)
+		v, err = s.handleFiles(ctx, action, params)
 	case "groups":
-		v, err = s.handleGroups(action, params)
+		v, err = s.handleGroups(ctx, action, params)
 	case "im":
-		v, err = s.handleIM(action, params)
+		v, err = s.handleIM(ctx, action, params)
+	case "mpim":
+		v, err = s.handleMpIM(ctx, action, params)
+	case "local":
+		v, err = s.handleLocal(ctx, action, params)
 	case "oauth":
 		err = fmt.Errorf("%s has not been supported", tp)
+	case "pins":
+		v, err = s.handlePins(ctx, action, params)
+	case "reactions":
+		v, err = s.handleReactions(ctx, action, params)
 	case "rtm":
 		err = fmt.Errorf("%s has not been supported", tp)
+	case "reminders":
+		v, err = s.handleReminders(ctx, action, params)
 	case "search":
-		v, err = s.handleSearch(action, params)
+		v, err = s.handleSearch(ctx, action, params)
 	case "stars":
-		v, err = s.handleStars(action, params)
+		v, err = s.handleStars(ctx, action, params)
+	case "team":
+		v, err = s.handleTeam(ctx, action, params)
+	case "thread":
+		v, err = s.handleThread(ctx, action, params)
+	case "usergroups":
+		v, err = s.handleUsergroups(ctx, action, params)
 	case "users":
-		v, err = s.handleUsers(action, params)
+		v, err = s.handleUsers(ctx, action, params)
 	default:
 		return nil, fmt.Errorf("unsupported api type %s", cmds[0])
 	}
 
+	// a command which finished before the cancel keeps its result
+	if err != nil {
+		switch ctx.Err() {
+		case context.Canceled:
+			v, err = nil, fmt.Errorf("%s canceled", cmd)
+		case context.DeadlineExceeded:
+			v, err = nil, fmt.Errorf("%s timed out", cmd)
+		}
+	}
+
+	if dryRun && isMutating(cmd) {
+		// the result is built from empty responses, don't show it
+		return nil, err
+	}
+
+	if err == nil {
+		// only tell about undo once the command did something to undo
+		if undo != nil {
+			s.recordUndo(undo, v)
+		} else if undoErr != nil {
+			fmt.Printf("undo is not available: %s\n", undoErr.Error())
+		}
+	}
+
+	if isMutating(cmd) {
+		s.audit(cmd, params, v, err)
+	}
+
 	return v, err
 }
 
@@ -102,15 +198,15 @@ func getBoolParam(params map[string]string, key string, defValue bool) bool {
 	}
 }
 
-func (s *Slack) handleChannels(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleChannels(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
 	switch action {
 	case "archive":
-		err = s.s.ArchiveChannel(params["channel"])
+		err = s.s.ArchiveChannel(ctx, params["channel"])
 	case "create":
-		ch, err := s.s.CreateChannel(params["name"])
+		ch, err := s.s.CreateChannel(ctx, params["name"])
 		if err != nil {
 			return nil, err
 		}
@@ -122,9 +218,19 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 		historyParam.Latest = getStringParam(params, "latest", slack.DEFAULT_HISTORY_LATEST)
 		historyParam.Oldest = getStringParam(params, "latest", slack.DEFAULT_HISTORY_OLDEST)
 		historyParam.Count = getIntParam(params, "count", slack.DEFAULT_HISTORY_COUNT)
-		v, err = s.s.GetChannelHistory(params["channel"], historyParam)
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		h, err := s.s.GetChannelHistory(ctx, channel, historyParam)
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, h.Messages)
+		markThreads(channel, h.Messages)
+		v = h
 	case "info":
-		ch, err := s.s.GetChannelInfo(params["channel"])
+		ch, err := s.s.GetChannelInfo(ctx, params["channel"])
 		if err != nil {
 			return nil, err
 		}
@@ -132,7 +238,7 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"channel": ch,
 		}
 	case "invite":
-		ch, err := s.s.InviteUserToChannel(params["channel"], params["user"])
+		ch, err := s.s.InviteUserToChannel(ctx, params["channel"], params["user"])
 		if err != nil {
 			return nil, err
 		}
@@ -140,7 +246,7 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"channel": ch,
 		}
 	case "join":
-		ch, err := s.s.JoinChannel(params["name"])
+		ch, err := s.s.JoinChannel(ctx, params["name"])
 		if err != nil {
 			return nil, err
 		}
@@ -148,12 +254,12 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"channel": ch,
 		}
 	case "kick":
-		err = s.s.KickUserFromChannel(params["channel"], params["user"])
+		err = s.s.KickUserFromChannel(ctx, params["channel"], params["user"])
 	case "leave":
-		_, err = s.s.LeaveChannel(params["channel"])
+		_, err = s.s.LeaveChannel(ctx, params["channel"])
 	case "list":
 		exclude := getIntParam(params, "exclude_archived", 0)
-		chs, err := s.s.GetChannels(exclude == 1)
+		chs, err := s.s.GetChannels(ctx, exclude == 1)
 		if err != nil {
 			return nil, err
 		}
@@ -161,9 +267,9 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"channels": chs,
 		}
 	case "mark":
-		err = s.s.SetChannelReadMark(params["channel"], params["ts"])
+		err = s.s.SetChannelReadMark(ctx, params["channel"], params["ts"])
 	case "rename":
-		ch, err := s.s.RenameChannel(params["channel"], params["name"])
+		ch, err := s.s.RenameChannel(ctx, params["channel"], params["name"])
 		if err != nil {
 			return nil, err
 		}
@@ -171,7 +277,7 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"channel": ch,
 		}
 	case "setpurpose":
-		purpose, err := s.s.SetChannelPurpose(params["channel"], params["purpose"])
+		purpose, err := s.s.SetChannelPurpose(ctx, params["channel"], params["purpose"])
 		if err != nil {
 			return nil, err
 		}
@@ -179,7 +285,7 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"purpose": purpose,
 		}
 	case "settopic":
-		topic, err := s.s.SetChannelTopic(params["channel"], params["topic"])
+		topic, err := s.s.SetChannelTopic(ctx, params["channel"], params["topic"])
 		if err != nil {
 			return nil, err
 		}
@@ -187,23 +293,23 @@ func (s *Slack) handleChannels(action string, params map[string]string) (interfa
 			"topic": topic,
 		}
 	case "unarchive":
-		err = s.s.UnarchiveChannel(params["channel"])
+		err = s.s.UnarchiveChannel(ctx, params["channel"])
 	default:
-		return nil, fmt.Errorf("invalid files action %s", action)
+		return nil, fmt.Errorf("invalid channels action %s", action)
 	}
 
 	return v, err
 }
 
-func (s *Slack) handleGroups(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleGroups(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
 	switch action {
 	case "archive":
-		err = s.s.ArchiveGroup(params["channel"])
+		err = s.s.ArchiveGroup(ctx, params["channel"])
 	case "close":
-		noop, closed, err := s.s.CloseGroup(params["channel"])
+		noop, closed, err := s.s.CloseGroup(ctx, params["channel"])
 		if err != nil {
 			return nil, err
 		}
@@ -212,7 +318,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"already_closed": closed,
 		}
 	case "create":
-		group, err := s.s.CreateGroup(params["name"])
+		group, err := s.s.CreateGroup(ctx, params["name"])
 		if err != nil {
 			return nil, err
 		}
@@ -220,7 +326,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"group": group,
 		}
 	case "createchild":
-		group, err := s.s.CreateChildGroup(params["channel"])
+		group, err := s.s.CreateChildGroup(ctx, params["channel"])
 		if err != nil {
 			return nil, err
 		}
@@ -232,9 +338,19 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 		historyParam.Latest = getStringParam(params, "latest", slack.DEFAULT_HISTORY_LATEST)
 		historyParam.Oldest = getStringParam(params, "latest", slack.DEFAULT_HISTORY_OLDEST)
 		historyParam.Count = getIntParam(params, "count", slack.DEFAULT_HISTORY_COUNT)
-		v, err = s.s.GetGroupHistory(params["channel"], historyParam)
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		h, err := s.s.GetGroupHistory(ctx, channel, historyParam)
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, h.Messages)
+		markThreads(channel, h.Messages)
+		v = h
 	case "invite":
-		group, in, err := s.s.InviteUserToGroup(params["channel"], params["user"])
+		group, in, err := s.s.InviteUserToGroup(ctx, params["channel"], params["user"])
 		if err != nil {
 			return nil, err
 		}
@@ -243,12 +359,12 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"group":            group,
 		}
 	case "kick":
-		err = s.s.KickUserFromGroup(params["channel"], params["user"])
+		err = s.s.KickUserFromGroup(ctx, params["channel"], params["user"])
 	case "leave":
-		err = s.s.LeaveGroup(params["channel"])
+		err = s.s.LeaveGroup(ctx, params["channel"])
 	case "list":
 		exclude := getIntParam(params, "exclude_archived", 0)
-		groups, err := s.s.GetGroups(exclude == 1)
+		groups, err := s.s.GetGroups(ctx, exclude == 1)
 		if err != nil {
 			return nil, err
 		}
@@ -256,7 +372,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"groups": groups,
 		}
 	case "open":
-		noop, opened, err := s.s.OpenGroup(params["channel"])
+		noop, opened, err := s.s.OpenGroup(ctx, params["channel"])
 		if err != nil {
 			return nil, err
 		}
@@ -265,9 +381,9 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"already_open": opened,
 		}
 	case "mark":
-		err = s.s.SetGroupReadMark(params["channel"], params["ts"])
+		err = s.s.SetGroupReadMark(ctx, params["channel"], params["ts"])
 	case "rename":
-		group, err := s.s.RenameGroup(params["channel"], params["name"])
+		group, err := s.s.RenameGroup(ctx, params["channel"], params["name"])
 		if err != nil {
 			return nil, err
 		}
@@ -275,7 +391,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"group": group,
 		}
 	case "setpurpose":
-		purpose, err := s.s.SetGroupPurpose(params["channel"], params["purpose"])
+		purpose, err := s.s.SetGroupPurpose(ctx, params["channel"], params["purpose"])
 		if err != nil {
 			return nil, err
 		}
@@ -283,7 +399,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"purpose": purpose,
 		}
 	case "settopic":
-		topic, err := s.s.SetGroupTopic(params["channel"], params["topic"])
+		topic, err := s.s.SetGroupTopic(ctx, params["channel"], params["topic"])
 		if err != nil {
 			return nil, err
 		}
@@ -291,7 +407,7 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 			"topic": topic,
 		}
 	case "unarchive":
-		err = s.s.UnarchiveGroup(params["channel"])
+		err = s.s.UnarchiveGroup(ctx, params["channel"])
 	default:
 		return nil, fmt.Errorf("invalid groups action %s", action)
 	}
@@ -299,17 +415,17 @@ func (s *Slack) handleGroups(action string, params map[string]string) (interface
 	return v, err
 }
 
-func (s *Slack) handleFiles(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleFiles(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
 	switch action {
 	case "delete":
-		err = s.s.DeleteFile(params["file"])
+		err = s.s.DeleteFile(ctx, params["file"])
 	case "info":
 		count := getIntParam(params, "count", slack.DEFAULT_FILES_COUNT)
 		page := getIntParam(params, "page", slack.DEFAULT_FILES_PAGE)
-		files, comments, pages, err := s.s.GetFileInfo(params["file"], count, page)
+		files, comments, pages, err := s.s.GetFileInfo(ctx, params["file"], count, page)
 		if err != nil {
 			return nil, err
 		}
@@ -327,7 +443,7 @@ func (s *Slack) handleFiles(action string, params map[string]string) (interface{
 		listParam.Count = getIntParam(params, "count", slack.DEFAULT_FILES_COUNT)
 		listParam.Page = getIntParam(params, "page", slack.DEFAULT_FILES_PAGE)
 
-		files, pages, err := s.s.GetFiles(listParam)
+		files, pages, err := s.s.GetFiles(ctx, listParam)
 		if err != nil {
 			return nil, err
 		}
@@ -347,7 +463,7 @@ func (s *Slack) handleFiles(action string, params map[string]string) (interface{
 		uploadParams.InitialComment = getStringParam(params, "initial_comment", "")
 		channels := getStringParam(params, "channels", "")
 		uploadParams.Channels = strings.Split(channels, ",")
-		file, err := s.s.UploadFile(uploadParams)
+		file, err := s.s.UploadFile(ctx, uploadParams)
 		if err != nil {
 			return nil, err
 		}
@@ -360,13 +476,13 @@ func (s *Slack) handleFiles(action string, params map[string]string) (interface{
 	return v, err
 }
 
-func (s *Slack) handleChat(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleChat(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
 	switch action {
 	case "delete":
-		ch, ts, err := s.s.DeleteMessage(params["channel"], params["ts"])
+		ch, ts, err := s.s.DeleteMessage(ctx, params["channel"], params["ts"])
 		if err != nil {
 			return nil, err
 		}
@@ -375,21 +491,18 @@ func (s *Slack) handleChat(action string, params map[string]string) (interface{}
 			"ts":      ts,
 		}
 	case "postmessage":
-		postParam := slack.PostMessageParameters{}
-		postParam.Username = getStringParam(params, "username", slack.DEFAULT_MESSAGE_USERNAME)
-		postParam.Parse = getStringParam(params, "parse", slack.DEFAULT_MESSAGE_PARSE)
-		postParam.LinkNames = getIntParam(params, "link_names", slack.DEFAULT_MESSAGE_LINK_NAMES)
-
-		postParam.UnfurlLinks = getBoolParam(params, "unfurl_links", slack.DEFAULT_MESSAGE_UNFURL_LINKS)
-		postParam.UnfurlMedia = getBoolParam(params, "unfurl_media", slack.DEFAULT_MESSAGE_UNFURL_MEDIA)
-		postParam.IconURL = getStringParam(params, "icon_url", slack.DEFAULT_MESSAGE_ICON_URL)
-		postParam.IconEmoji = getStringParam(params, "icon_emoji", slack.DEFAULT_MESSAGE_ICON_EMOJI)
-
-		if err = json.Unmarshal([]byte(getStringParam(params, "attachments", "[]")), &postParam.Attachments); err != nil {
+		postParam, err := postMessageParameters(params)
+		if err != nil {
 			return nil, err
 		}
 
-		ch, ts, err := s.s.PostMessage(params["channel"], params["text"], postParam)
+		var ch, ts string
+		if threadTs := params["thread_ts"]; len(threadTs) > 0 {
+			broadcast := getBoolParam(params, "reply_broadcast", false)
+			ch, ts, err = s.s.PostThreadMessage(ctx, params["channel"], threadTs, params["text"], broadcast, postParam)
+		} else {
+			ch, ts, err = s.s.PostMessage(ctx, params["channel"], params["text"], postParam)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -398,8 +511,25 @@ func (s *Slack) handleChat(action string, params map[string]string) (interface{}
 			"channel": ch,
 			"ts":      ts,
 		}
+	case "reply":
+		postParam, err := postMessageParameters(params)
+		if err != nil {
+			return nil, err
+		}
+
+		broadcast := getBoolParam(params, "broadcast", false)
+		ch, ts, err := s.s.PostThreadMessage(ctx, params["channel"], params["ts"], params["text"], broadcast, postParam)
+		if err != nil {
+			return nil, err
+		}
+
+		v = map[string]string{
+			"channel":   ch,
+			"ts":        ts,
+			"thread_ts": params["ts"],
+		}
 	case "update":
-		ch, ts, text, err := s.s.UpdateMessage(params["channel"], params["ts"], params["text"])
+		ch, ts, text, err := s.s.UpdateMessage(ctx, params["channel"], params["ts"], params["text"])
 		if err != nil {
 			return nil, err
 		}
@@ -415,12 +545,27 @@ func (s *Slack) handleChat(action string, params map[string]string) (interface{}
 	return v, err
 }
 
-func (s *Slack) handleEmoji(action string, params map[string]string) (interface{}, error) {
+func postMessageParameters(params map[string]string) (slack.PostMessageParameters, error) {
+	postParam := slack.PostMessageParameters{}
+	postParam.Username = getStringParam(params, "username", slack.DEFAULT_MESSAGE_USERNAME)
+	postParam.Parse = getStringParam(params, "parse", slack.DEFAULT_MESSAGE_PARSE)
+	postParam.LinkNames = getIntParam(params, "link_names", slack.DEFAULT_MESSAGE_LINK_NAMES)
+
+	postParam.UnfurlLinks = getBoolParam(params, "unfurl_links", slack.DEFAULT_MESSAGE_UNFURL_LINKS)
+	postParam.UnfurlMedia = getBoolParam(params, "unfurl_media", slack.DEFAULT_MESSAGE_UNFURL_MEDIA)
+	postParam.IconURL = getStringParam(params, "icon_url", slack.DEFAULT_MESSAGE_ICON_URL)
+	postParam.IconEmoji = getStringParam(params, "icon_emoji", slack.DEFAULT_MESSAGE_ICON_EMOJI)
+
+	err := json.Unmarshal([]byte(getStringParam(params, "attachments", "[]")), &postParam.Attachments)
+	return postParam, err
+}
+
+func (s *Slack) handleEmoji(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 	switch action {
 	case "list":
-		m, err := s.s.GetEmoji()
+		m, err := s.s.GetEmoji(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -435,13 +580,13 @@ func (s *Slack) handleEmoji(action string, params map[string]string) (interface{
 	return v, err
 }
 
-func (s *Slack) handleIM(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleIM(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
 	switch action {
 	case "close":
-		noop, closed, err := s.s.CloseIMChannel(params["channel"])
+		noop, closed, err := s.s.CloseIMChannel(ctx, params["channel"])
 		if err != nil {
 			return nil, err
 		}
@@ -458,10 +603,20 @@ func (s *Slack) handleIM(action string, params map[string]string) (interface{},
 
 		historyParms.Count = getIntParam(params, "count", slack.DEFAULT_HISTORY_COUNT)
 
-		v, err = s.s.GetIMHistory(params["channel"], historyParms)
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		h, err := s.s.GetIMHistory(ctx, channel, historyParms)
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, h.Messages)
+		markThreads(channel, h.Messages)
+		v = h
 
 	case "list":
-		ims, err := s.s.GetIMChannels()
+		ims, err := s.s.GetIMChannels(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -470,9 +625,9 @@ func (s *Slack) handleIM(action string, params map[string]string) (interface{},
 			"ims": ims,
 		}
 	case "mark":
-		err = s.s.MarkIMChannel(params["channel"], params["ts"])
+		err = s.s.MarkIMChannel(ctx, params["channel"], params["ts"])
 	case "open":
-		noop, opened, ch, err := s.s.OpenIMChannel(params["user"])
+		noop, opened, ch, err := s.s.OpenIMChannel(ctx, params["user"])
 		if err != nil {
 			return nil, err
 		}
@@ -492,7 +647,7 @@ func (s *Slack) handleIM(action string, params map[string]string) (interface{},
 	return v, err
 }
 
-func (s *Slack) handleSearch(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleSearch(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 
@@ -510,7 +665,7 @@ func (s *Slack) handleSearch(action string, params map[string]string) (interface
 
 	switch action {
 	case "all":
-		m, f, err := s.s.Search(query, searchParams)
+		m, f, err := s.s.Search(ctx, query, searchParams)
 		if err != nil {
 			return nil, err
 		}
@@ -522,7 +677,7 @@ func (s *Slack) handleSearch(action string, params map[string]string) (interface
 		}
 
 	case "files":
-		f, err := s.s.SearchFiles(query, searchParams)
+		f, err := s.s.SearchFiles(ctx, query, searchParams)
 		if err != nil {
 			return nil, err
 		}
@@ -532,7 +687,7 @@ func (s *Slack) handleSearch(action string, params map[string]string) (interface
 			"files": f,
 		}
 	case "messages":
-		m, err := s.s.SearchMessages(query, searchParams)
+		m, err := s.s.SearchMessages(ctx, query, searchParams)
 		if err != nil {
 			return nil, err
 		}
@@ -549,10 +704,91 @@ func (s *Slack) handleSearch(action string, params map[string]string) (interface
 	return v, err
 }
 
-func (s *Slack) handleStars(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) indexHistory(channel string, msgs []Message) {
+	if s.index == nil {
+		return
+	}
+	if err := s.index.add(channel, msgs); err != nil {
+		fmt.Printf("index history err: %s\n", err.Error())
+	}
+}
+
+func (s *Slack) handleLocal(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "search":
+		if s.index == nil {
+			return nil, fmt.Errorf("local index is not enabled")
+		}
+
+		searchParams := localSearchParameters{}
+		searchParams.Sort = getStringParam(params, "sort", slack.DEFAULT_SEARCH_SORT)
+		searchParams.SortDirection = getStringParam(params, "sort_dir", slack.DEFAULT_SEARCH_SORT_DIR)
+		highlight := getIntParam(params, "highlight", 0)
+		searchParams.Highlight = (highlight == 1)
+
+		searchParams.Count = getIntParam(params, "count", slack.DEFAULT_SEARCH_COUNT)
+		searchParams.Page = getIntParam(params, "page", slack.DEFAULT_SEARCH_PAGE)
+
+		if searchParams.From, err = s.resolveUser(ctx, params["from"]); err != nil {
+			return nil, err
+		}
+		if searchParams.In, err = s.resolveChannel(ctx, params["in"]); err != nil {
+			return nil, err
+		}
+		if searchParams.After, err = parseDateParam(params["after"]); err != nil {
+			return nil, err
+		}
+		if searchParams.Before, err = parseDateParam(params["before"]); err != nil {
+			return nil, err
+		}
+
+		query := params["query"]
+		if len(tokenize(query)) == 0 && len(searchParams.From) == 0 && len(searchParams.In) == 0 &&
+			searchParams.After == 0 && searchParams.Before == 0 {
+			return nil, fmt.Errorf("query or one of from, in, after and before is required")
+		}
+		v = map[string]interface{}{
+			"query":    query,
+			"messages": s.index.search(query, searchParams),
+		}
+	default:
+		return nil, fmt.Errorf("invalid local action %s", action)
+	}
+
+	return v, err
+}
+
+func (s *Slack) handleStars(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 	switch action {
+	case "add":
+		err = s.s.AddStar(ctx, itemRefParams(params))
+	case "export":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		name := getStringParam(params, "output", "stars.md")
+		if s.isDryRun(params) {
+			// the stars are read but not written in a dry run
+			return map[string]interface{}{
+				"output":  name,
+				"written": false,
+			}, nil
+		}
+		n, err := s.exportStars(ctx, user, name)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"output": name,
+			"count":  n,
+		}
 	case "list":
 		starsParams := slack.StarsParameters{}
 		starsParams.User = params["user"]
@@ -560,7 +796,7 @@ func (s *Slack) handleStars(action string, params map[string]string) (interface{
 		starsParams.Count = getIntParam(params, "count", slack.DEFAULT_STARS_COUNT)
 		starsParams.Page = getIntParam(params, "page", slack.DEFAULT_STARS_PAGE)
 
-		items, paging, err := s.s.GetStarred(starsParams)
+		items, paging, err := s.s.GetStarred(ctx, starsParams)
 		if err != nil {
 			return nil, err
 		}
@@ -568,6 +804,8 @@ func (s *Slack) handleStars(action string, params map[string]string) (interface{
 			"items":  items,
 			"paging": paging,
 		}
+	case "remove":
+		err = s.s.RemoveStar(ctx, itemRefParams(params))
 	default:
 		return nil, fmt.Errorf("invalid stars action %s", action)
 	}
@@ -575,20 +813,70 @@ func (s *Slack) handleStars(action string, params map[string]string) (interface{
 	return v, err
 }
 
-func (s *Slack) handleUsers(action string, params map[string]string) (interface{}, error) {
+func (s *Slack) handleUsers(ctx context.Context, action string, params map[string]string) (interface{}, error) {
 	var v interface{}
 	var err error
 	switch action {
 	case "getpresence":
-		v, err = s.s.GetUserPresence(params["user"])
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		presence, err := s.s.GetUserPresence(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		// without the dnd:read scope the presence is still worth showing
+		p := presenceDND{UserPresence: presence}
+		if p.DND, err = s.s.GetDNDInfo(ctx, user); err != nil {
+			p.DNDError = err.Error()
+		}
+		v = p
 	case "info":
-		v, err = s.s.GetUserInfo(params["user"])
+		v, err = s.s.GetUserInfo(ctx, params["user"])
 	case "list":
-		v, err = s.s.GetUsers()
+		v, err = s.s.GetUsers(ctx)
+	case "profile.get":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		profile, err := s.s.GetUserProfile(ctx, user, getBoolParam(params, "include_labels", false))
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"profile": profile,
+		}
+	case "profile.set":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		update, err := s.profileUpdate(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		profile, err := s.s.SetUserProfile(ctx, user, update)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"profile": profile,
+		}
 	case "setactive":
-		err = s.s.SetUserAsActive()
+		err = s.s.SetUserAsActive(ctx)
+	case "setphoto":
+		if len(params["image"]) == 0 {
+			return nil, fmt.Errorf("image is required")
+		}
+		err = s.s.SetUserPhoto(ctx, params["image"],
+			getIntParam(params, "crop_x", 0), getIntParam(params, "crop_y", 0), getIntParam(params, "crop_w", 0))
 	case "setpresence":
-		err = s.s.SetUserPresence(params["presence"])
+		err = s.s.SetUserPresence(ctx, params["presence"])
 	default:
 		return nil, fmt.Errorf("invalid users action %s", action)
 	}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExtractParams(t *testing.T) {
+	tests := []struct {
+		args []string
+		want map[string]string
+	}{
+		{nil, map[string]string{}},
+		{[]string{"channel=C024BE91L"}, map[string]string{"channel": "C024BE91L"}},
+		{[]string{"text=a=b"}, map[string]string{"text": "a=b"}},
+		{[]string{`text="hello world"`}, map[string]string{"text": "hello world"}},
+		{[]string{"text='hello'"}, map[string]string{"text": "hello"}},
+		{[]string{"text="}, map[string]string{"text": ""}},
+		{[]string{"pretty"}, map[string]string{"pretty": ""}},
+		{[]string{"count=1", "count=2"}, map[string]string{"count": "2"}},
+		{[]string{"user=@alice", "all=true"}, map[string]string{"user": "@alice", "all": "true"}},
+	}
+
+	for _, tt := range tests {
+		if got := extractParams(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractParams(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestGetIntParam(t *testing.T) {
+	params := map[string]string{"count": "20", "page": "two", "empty": ""}
+	tests := []struct {
+		key      string
+		defValue int
+		want     int
+	}{
+		{"count", 100, 20},
+		{"page", 1, 1},
+		{"empty", 5, 5},
+		{"missing", 7, 7},
+	}
+
+	for _, tt := range tests {
+		if got := getIntParam(params, tt.key, tt.defValue); got != tt.want {
+			t.Errorf("getIntParam(%s, %d) = %d, want %d", tt.key, tt.defValue, got, tt.want)
+		}
+	}
+}
+
+func TestGetBoolParam(t *testing.T) {
+	params := map[string]string{"all": "true", "table": "false", "broadcast": "1", "empty": ""}
+	tests := []struct {
+		key      string
+		defValue bool
+		want     bool
+	}{
+		{"all", false, true},
+		{"table", true, false},
+		{"broadcast", true, false},
+		{"empty", true, false},
+		{"missing", true, true},
+		{"missing", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := getBoolParam(params, tt.key, tt.defValue); got != tt.want {
+			t.Errorf("getBoolParam(%s, %v) = %v, want %v", tt.key, tt.defValue, got, tt.want)
+		}
+	}
+}
+
+func TestHandleDispatch(t *testing.T) {
+	s, _ := newTestSlack(t)
+
+	tests := []struct {
+		cmd  string
+		args []string
+		err  string
+	}{
+		{"users", nil, "cmd must be type.action format, not users"},
+		{"foo.list", nil, "unsupported api type foo"},
+		{"rtm.start", nil, "rtm has not been supported"},
+		{"channels.foo", nil, "invalid channels action foo"},
+		{"users.profile.foo", nil, "invalid users action profile.foo"},
+		{"users.list", nil, ""},
+		{"USERS.LIST", nil, ""},
+		{"users.profile.get", nil, ""},
+		{"usergroups.users.list", nil, "usergroup is required"},
+		{"channels.list", []string{"exclude_archived=1"}, ""},
+	}
+
+	for _, tt := range tests {
+		_, err := s.handle(context.Background(), tt.cmd, tt.args)
+		switch {
+		case len(tt.err) == 0 && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.cmd, err)
+		case len(tt.err) > 0 && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: error %v, want %s", tt.cmd, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+func (c *apiClient) AddStar(ctx context.Context, item itemRef) error {
+	return c.call(ctx, "stars.add", item.values(), nil)
+}
+
+func (c *apiClient) RemoveStar(ctx context.Context, item itemRef) error {
+	return c.call(ctx, "stars.remove", item.values(), nil)
+}
+
+func (c *apiClient) GetStarred(ctx context.Context, params slack.StarsParameters) ([]slack.StarredItem, *slack.Paging, error) {
+	values := url.Values{}
+	if params.User != slack.DEFAULT_STARS_USER {
+		values.Set("user", params.User)
+	}
+	if params.Count != slack.DEFAULT_STARS_COUNT {
+		values.Set("count", strconv.Itoa(params.Count))
+	}
+	if params.Page != slack.DEFAULT_STARS_PAGE {
+		values.Set("page", strconv.Itoa(params.Page))
+	}
+
+	var r struct {
+		Items  []slack.StarredItem `json:"items"`
+		Paging *slack.Paging       `json:"paging"`
+	}
+	if err := c.call(ctx, "stars.list", values, &r); err != nil {
+		return nil, nil, err
+	}
+	return r.Items, r.Paging, nil
+}
+
+// allStarred walks every page of the starred items of user.
+func (s *Slack) allStarred(ctx context.Context, user string) ([]slack.StarredItem, error) {
+	var items []slack.StarredItem
+	for page := 1; ; page++ {
+		starsParams := slack.StarsParameters{}
+		starsParams.User = user
+		starsParams.Count = slack.DEFAULT_STARS_COUNT
+		starsParams.Page = page
+
+		starred, paging, err := s.s.GetStarred(ctx, starsParams)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, starred...)
+
+		if paging == nil || page >= paging.Pages || len(starred) == 0 {
+			return items, nil
+		}
+	}
+}
+
+// permalink builds the archive link of a channel, or of a message if ts is set.
+func permalink(teamURL string, channel string, ts string) string {
+	link := strings.TrimSuffix(teamURL, "/") + "/archives/" + channel
+	if len(ts) > 0 {
+		link += "/p" + strings.Replace(ts, ".", "", 1)
+	}
+	return link
+}
+
+func markdownText(text string) string {
+	text = truncate(text, 80)
+	text = strings.Replace(text, "[", `\[`, -1)
+	return strings.Replace(text, "]", `\]`, -1)
+}
+
+// timestampDate shows the day of a slack timestamp.
+func timestampDate(ts string) string {
+	sec, err := strconv.ParseInt(strings.SplitN(ts, ".", 2)[0], 10, 64)
+	if err != nil {
+		return ts
+	}
+	return time.Unix(sec, 0).Format("2006-01-02")
+}
+
+// exportStars writes the starred items of user as a Markdown list of links.
+func (s *Slack) exportStars(ctx context.Context, user string, name string) (int, error) {
+	auth, err := s.s.AuthTest(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	items, err := s.allStarred(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	// previews show ids if the users can't be listed
+	names, _ := s.userNames(ctx)
+	channels := make(map[string]string)
+	channelName := func(id string) string {
+		if _, ok := channels[id]; !ok {
+			channels[id] = strings.SplitN(s.channelName(ctx, id), " ", 2)[0]
+		}
+		return channels[id]
+	}
+	userName := func(id string) string {
+		if name, ok := names[id]; ok {
+			return "@" + name
+		}
+		return id
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# Stars of %s in %s\n\n", auth.User, auth.Team)
+	for _, item := range items {
+		switch {
+		case item.Type == "message" && item.Message != nil:
+			msg := item.Message
+			fmt.Fprintf(w, "- [%s: %s](%s) by %s, %s\n", channelName(item.ChannelId),
+				markdownText(s.renderMentions(ctx, msg.Text, names)),
+				permalink(auth.Url, item.ChannelId, msg.Timestamp), userName(msg.User), timestampDate(msg.Timestamp))
+		case item.File != nil:
+			title := item.File.Title
+			if len(title) == 0 {
+				title = item.File.Name
+			}
+			if item.Comment != nil {
+				title = fmt.Sprintf("%s: %s", title, item.Comment.Comment)
+			}
+			fmt.Fprintf(w, "- [%s](%s)", markdownText(title), item.File.Permalink)
+			if len(item.File.User) > 0 {
+				fmt.Fprintf(w, " by %s", userName(item.File.User))
+			}
+			fmt.Fprintf(w, "\n")
+		case len(item.ChannelId) > 0:
+			fmt.Fprintf(w, "- [%s](%s)\n", channelName(item.ChannelId), permalink(auth.Url, item.ChannelId, ""))
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+type TeamInfo struct {
+	Id          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Domain      string                 `json:"domain"`
+	EmailDomain string                 `json:"email_domain"`
+	Icon        map[string]interface{} `json:"icon"`
+}
+
+// AccessLog is the logins of a user from one IP address and user agent.
+type AccessLog struct {
+	UserId    string `json:"user_id"`
+	Username  string `json:"username"`
+	DateFirst int64  `json:"date_first"`
+	DateLast  int64  `json:"date_last"`
+	Count     int    `json:"count"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	ISP       string `json:"isp"`
+	Country   string `json:"country"`
+	Region    string `json:"region"`
+}
+
+type BillableInfo struct {
+	BillingActive bool `json:"billing_active"`
+}
+
+func (c *apiClient) GetTeamInfo(ctx context.Context) (*TeamInfo, error) {
+	var r struct {
+		Team *TeamInfo `json:"team"`
+	}
+	if err := c.call(ctx, "team.info", url.Values{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Team, nil
+}
+
+// GetAccessLogs returns a page of logins, before limits them to the ones
+// before a unix time if not 0.
+func (c *apiClient) GetAccessLogs(ctx context.Context, count int, page int, before int64) ([]AccessLog, *slack.Paging, error) {
+	values := url.Values{
+		"count": {strconv.Itoa(count)},
+		"page":  {strconv.Itoa(page)},
+	}
+	if before > 0 {
+		values.Set("before", strconv.FormatInt(before, 10))
+	}
+
+	var r struct {
+		Logins []AccessLog  `json:"logins"`
+		Paging slack.Paging `json:"paging"`
+	}
+	if err := c.call(ctx, "team.accessLogs", values, &r); err != nil {
+		return nil, nil, err
+	}
+	return r.Logins, &r.Paging, nil
+}
+
+// GetBillableInfo returns the billing state of user, or of every user if empty.
+func (c *apiClient) GetBillableInfo(ctx context.Context, user string) (map[string]BillableInfo, error) {
+	values := url.Values{}
+	if len(user) > 0 {
+		values.Set("user", user)
+	}
+
+	var r struct {
+		BillableInfo map[string]BillableInfo `json:"billable_info"`
+	}
+	if err := c.call(ctx, "team.billableInfo", values, &r); err != nil {
+		return nil, err
+	}
+	return r.BillableInfo, nil
+}
+
+// maxAccessLogsCount is the largest page of team.accessLogs, a tier 2 method,
+// so walking takes as few calls as it can.
+const maxAccessLogsCount = 1000
+
+// allAccessLogs walks the pages of logins, newest first, until they are
+// older than since if it is not 0.
+func (s *Slack) allAccessLogs(ctx context.Context, before int64, since float64) ([]AccessLog, error) {
+	var logins []AccessLog
+	for page := 1; ; page++ {
+		l, paging, err := s.s.GetAccessLogs(ctx, maxAccessLogsCount, page, before)
+		if err != nil {
+			return nil, err
+		}
+		logins = append(logins, l...)
+
+		if paging == nil || page >= paging.Pages || len(l) == 0 {
+			return logins, nil
+		}
+		if since > 0 && float64(l[len(l)-1].DateLast) < since {
+			return logins, nil
+		}
+	}
+}
+
+// accessLogTable shows the logins one per row, for reading rather than scripts.
+func accessLogTable(logins []AccessLog) *table {
+	t := &table{header: []string{"USER", "IP", "USER AGENT", "COUNT", "FIRST", "LAST", "COUNTRY"}}
+	for _, l := range logins {
+		t.rows = append(t.rows, []string{
+			l.Username, l.IP, l.UserAgent, strconv.Itoa(l.Count),
+			time.Unix(l.DateFirst, 0).Format("2006-01-02 15:04"),
+			time.Unix(l.DateLast, 0).Format("2006-01-02 15:04"),
+			l.Country,
+		})
+	}
+	return t
+}
+
+func (s *Slack) handleTeam(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "accesslogs":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		since, err := parseDateParam(params["since"])
+		if err != nil {
+			return nil, err
+		}
+		before, err := parseDateParam(params["before"])
+		if err != nil {
+			return nil, err
+		}
+
+		// the API filters neither by user nor by a start date, so a filter
+		// walks the pages instead of filtering one of them
+		var logins []AccessLog
+		var paging *slack.Paging
+		if len(user) > 0 || since > 0 {
+			logins, err = s.allAccessLogs(ctx, int64(before), since)
+		} else {
+			logins, paging, err = s.s.GetAccessLogs(ctx, getIntParam(params, "count", 100), getIntParam(params, "page", 1), int64(before))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		matches := []AccessLog{}
+		for _, l := range logins {
+			if len(user) > 0 && l.UserId != user {
+				continue
+			}
+			if since > 0 && float64(l.DateLast) < since {
+				continue
+			}
+			matches = append(matches, l)
+		}
+
+		if getBoolParam(params, "table", false) {
+			return accessLogTable(matches), nil
+		}
+		r := map[string]interface{}{
+			"logins": matches,
+		}
+		if paging != nil {
+			r["paging"] = paging
+		}
+		v = r
+	case "billableinfo":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := s.s.GetBillableInfo(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"billable_info": info,
+		}
+	case "info":
+		team, err := s.s.GetTeamInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"team": team,
+		}
+	default:
+		return nil, fmt.Errorf("invalid team action %s", action)
+	}
+
+	return v, err
+}
@@ -0,0 +1,72 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	t := new(syscall.Termios)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return nil, errno
+	}
+	return t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	_, err := getTermios(f.Fd())
+	return err == nil
+}
+
+// makeRaw puts the terminal in raw mode the way linenoise does,
+// and returns a function to restore it.
+func makeRaw(f *os.File) (func(), error) {
+	fd := f.Fd()
+	orig, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *orig
+	// no break, no CR to NL, no parity check, no strip char, no start/stop output control
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	// disable post processing
+	raw.Oflag &^= syscall.OPOST
+	// 8 bit chars
+	raw.Cflag |= syscall.CS8
+	// echo off, canonical off, no extended functions, no signal chars
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	// return each byte, or zero for timeout
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err = setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return func() {
+		setTermios(fd, orig)
+	}, nil
+}
+
+type winsize struct {
+	Row, Col, Xpixel, Ypixel uint16
+}
+
+func terminalColumns() int {
+	ws := new(winsize)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(ws)))
+	if errno != 0 || ws.Col == 0 {
+		return 80
+	}
+	return int(ws.Col)
+}
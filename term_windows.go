@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	enableProcessedInput      = 0x0001
+	enableLineInput           = 0x0002
+	enableEchoInput           = 0x0004
+	enableVirtualTerminalIn   = 0x0200
+	enableProcessedOutput     = 0x0001
+	enableVirtualTerminalProc = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode             = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+func getConsoleMode(f *os.File) (uint32, error) {
+	var mode uint32
+	r, _, err := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode)))
+	if r == 0 {
+		return 0, err
+	}
+	return mode, nil
+}
+
+func setConsoleMode(f *os.File, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	_, err := getConsoleMode(f)
+	return err == nil
+}
+
+// makeRaw turns off line input and echo, and turns on virtual terminal
+// sequences so the console speaks the same escape codes as unix terminals.
+func makeRaw(f *os.File) (func(), error) {
+	inMode, err := getConsoleMode(f)
+	if err != nil {
+		return nil, err
+	}
+	outMode, err := getConsoleMode(os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalIn
+	if err = setConsoleMode(f, raw); err != nil {
+		return nil, err
+	}
+	if err = setConsoleMode(os.Stdout, outMode|enableProcessedOutput|enableVirtualTerminalProc); err != nil {
+		setConsoleMode(f, inMode)
+		return nil, err
+	}
+
+	return func() {
+		setConsoleMode(f, inMode)
+		setConsoleMode(os.Stdout, outMode)
+	}, nil
+}
+
+type coord struct {
+	X, Y int16
+}
+
+type smallRect struct {
+	Left, Top, Right, Bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	Size              coord
+	CursorPosition    coord
+	Attributes        uint16
+	Window            smallRect
+	MaximumWindowSize coord
+}
+
+func terminalColumns() int {
+	var info consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 80
+	}
+	return int(info.Window.Right-info.Window.Left) + 1
+}
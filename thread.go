@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// markThreads sets the thread hint of thread parents and broadcast replies.
+func markThreads(channel string, msgs []Message) {
+	for i := range msgs {
+		m := &msgs[i]
+		switch {
+		case len(m.ThreadTs) == 0:
+		case m.ThreadTs == m.Timestamp && m.ReplyCount > 0:
+			m.Thread = fmt.Sprintf("%d replies, last %s, see thread.show channel=%s ts=%s", m.ReplyCount, m.LatestReply, channel, m.Timestamp)
+		case m.ThreadTs != m.Timestamp:
+			m.Thread = fmt.Sprintf("reply in thread %s", m.ThreadTs)
+		}
+	}
+}
+
+func (s *Slack) handleThread(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "show":
+		channel, err := s.resolveChannel(ctx, params["channel"])
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := s.s.GetThreadReplies(ctx, channel, params["ts"])
+		if err != nil {
+			return nil, err
+		}
+		s.indexHistory(channel, msgs)
+
+		v = map[string]interface{}{
+			"messages": msgs,
+		}
+	default:
+		return nil, fmt.Errorf("invalid thread action %s", action)
+	}
+
+	return v, err
+}
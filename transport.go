@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Web API rate limit tiers, calls per minute.
+// See https://api.slack.com/docs/rate-limits
+var tierLimits = map[int]int{
+	1: 1,
+	2: 20,
+	3: 50,
+	4: 100,
+}
+
+var methodTiers = map[string]int{
+	"auth.test":             4,
+	"channels.create":       2,
+	"channels.list":         2,
+	"chat.delete":           3,
+	"chat.postMessage":      4,
+	"chat.update":           3,
+	"conversations.create":  2,
+	"conversations.list":    2,
+	"conversations.members": 4,
+	"dnd.teamInfo":          2,
+	"emoji.list":            2,
+	"files.list":            3,
+	"files.upload":          2,
+	"groups.create":         2,
+	"groups.list":           2,
+	"im.list":               2,
+	"mpim.list":             2,
+	"reactions.list":        2,
+	"search.all":            2,
+	"search.files":          2,
+	"search.messages":       2,
+	"stars.list":            2,
+	"team.accessLogs":       2,
+	"team.billableInfo":     2,
+	"usergroups.list":       2,
+	"usergroups.users.list": 2,
+	"users.info":            4,
+	"users.list":            2,
+}
+
+func methodTier(method string) int {
+	if tier, ok := methodTiers[method]; ok {
+		return tier
+	}
+	return 3
+}
+
+// idempotentMethods only read, so calling them again has no side effect and
+// they are safe to retry whatever the rate limit response was.
+var idempotentMethods = map[string]bool{
+	"auth.test":             true,
+	"channels.history":      true,
+	"channels.info":         true,
+	"channels.list":         true,
+	"conversations.history": true,
+	"conversations.info":    true,
+	"conversations.list":    true,
+	"conversations.members": true,
+	"conversations.replies": true,
+	"dnd.info":              true,
+	"dnd.teamInfo":          true,
+	"emoji.list":            true,
+	"files.info":            true,
+	"files.list":            true,
+	"groups.history":        true,
+	"groups.list":           true,
+	"im.history":            true,
+	"im.list":               true,
+	"mpim.history":          true,
+	"mpim.list":             true,
+	"pins.list":             true,
+	"reactions.get":         true,
+	"reactions.list":        true,
+	"reminders.info":        true,
+	"reminders.list":        true,
+	"search.all":            true,
+	"search.files":          true,
+	"search.messages":       true,
+	"stars.list":            true,
+	"team.accessLogs":       true,
+	"team.billableInfo":     true,
+	"team.info":             true,
+	"team.profile.get":      true,
+	"usergroups.list":       true,
+	"usergroups.users.list": true,
+	"users.getPresence":     true,
+	"users.info":            true,
+	"users.list":            true,
+	"users.profile.get":     true,
+}
+
+func isIdempotent(method string) bool {
+	return idempotentMethods[method]
+}
+
+// isRefused reports whether slack answered with HTTP 429 and Retry-After, which
+// means the call was not processed, so any method can be sent again.
+func isRefused(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests && len(resp.Header.Get("Retry-After")) > 0
+}
+
+// baseURLTransport sends requests to an alternate Web API endpoint,
+// like a proxy or a fake server.
+type baseURLTransport struct {
+	base    http.RoundTripper
+	baseURL *url.URL
+}
+
+func newBaseURLTransport(base http.RoundTripper, apiURL string) (*baseURLTransport, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid api url %s, must be like http://host:port/api/", apiURL)
+	}
+	return &baseURLTransport{base: base, baseURL: u}, nil
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *t.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path.Base(req.URL.Path)
+	u.RawQuery = req.URL.RawQuery
+
+	r := req.Clone(req.Context())
+	r.URL = &u
+	r.Host = u.Host
+	return t.base.RoundTrip(r)
+}
+
+type rateLimitedError struct {
+	method     string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("%s is rate limited, retry after %s", e.method, e.retryAfter)
+}
+
+// rateLimitTransport sits under the slack client, throttles calls to stay within
+// each method's tier budget, and retries calls that hit rate limits when slack
+// refused them or they are idempotent.
+type rateLimitTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	verbose    bool
+
+	m     sync.Mutex
+	calls map[string][]time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, maxRetries int, verbose bool) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:       base,
+		maxRetries: maxRetries,
+		verbose:    verbose,
+		calls:      make(map[string][]time.Time),
+	}
+}
+
+func (t *rateLimitTransport) logf(format string, args ...interface{}) {
+	if t.verbose {
+		fmt.Printf("throttle: "+format+"\n", args...)
+	}
+}
+
+// reserve records a call to method and returns how long to wait before sending it.
+func (t *rateLimitTransport) reserve(method string) time.Duration {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	limit := tierLimits[methodTier(method)]
+	now := time.Now()
+
+	calls := t.calls[method]
+	for len(calls) > 0 && now.Sub(calls[0]) >= time.Minute {
+		calls = calls[1:]
+	}
+
+	var wait time.Duration
+	if len(calls) >= limit {
+		wait = calls[len(calls)-limit].Add(time.Minute).Sub(now)
+	}
+
+	t.calls[method] = append(calls, now.Add(wait))
+	return wait
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func backoff(attempt int) time.Duration {
+	d := minBackoff << uint(attempt)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	// full jitter between d/2 and d
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return backoff(attempt)
+}
+
+// isRateLimited checks both HTTP 429 and the ratelimited error in a 200 response,
+// body is replaced so the caller can still read it.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var r struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &r) != nil {
+		return false
+	}
+	return !r.Ok && r.Error == "ratelimited"
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if wait := t.reserve(method); wait > 0 {
+			t.logf("%s exceeds tier %d budget, waiting %s", method, methodTier(method), wait)
+			if err := sleep(req, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		r := req
+		if body != nil {
+			r = req.Clone(req.Context())
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || !isRateLimited(resp) {
+			return resp, err
+		}
+
+		wait := retryAfter(resp, attempt)
+		refused := isRefused(resp)
+		resp.Body.Close()
+
+		if !(refused || isIdempotent(method)) || attempt >= t.maxRetries {
+			t.logf("%s rate limited, giving up", method)
+			return nil, &rateLimitedError{method: method, retryAfter: wait}
+		}
+
+		t.logf("%s rate limited, retry %d/%d after %s", method, attempt+1, t.maxRetries, wait)
+		if err := sleep(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
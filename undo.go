@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+const maxUndo = 20
+
+// undoOp is a command that reverts a previous one.
+type undoOp struct {
+	desc   string
+	cmd    string
+	params map[string]string
+}
+
+// prepareUndo builds the undo operation for cmd before it runs, because some
+// commands like chat.update need the state they are going to overwrite.
+func (s *Slack) prepareUndo(ctx context.Context, cmd string, params map[string]string) (*undoOp, error) {
+	cmd = strings.ToLower(cmd)
+	channel := params["channel"]
+	tp := strings.SplitN(cmd, ".", 2)[0]
+	if tp == "conversations" && len(channel) > 0 {
+		var err error
+		if channel, err = s.resolveChannel(ctx, channel); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cmd {
+	case "chat.postmessage", "chat.reply":
+		// channel and ts are filled in by the result
+		return &undoOp{cmd: "chat.delete"}, nil
+	case "chat.update":
+		msg, err := s.getMessage(ctx, channel, params["ts"])
+		if err != nil {
+			return nil, err
+		}
+		return &undoOp{
+			desc:   fmt.Sprintf("restore message %s in %s, text only", params["ts"], channel),
+			cmd:    "chat.update",
+			params: map[string]string{"channel": channel, "ts": params["ts"], "text": msg.Text},
+		}, nil
+	case "reactions.add", "reactions.remove", "pins.add", "pins.remove", "stars.add", "stars.remove":
+		// the opposite action on the same item
+		op := &undoOp{cmd: tp + ".add", params: make(map[string]string)}
+		if strings.HasSuffix(cmd, ".add") {
+			op.cmd = tp + ".remove"
+		}
+		for _, key := range []string{"name", "channel", "ts", "file", "file_comment"} {
+			if value, ok := params[key]; ok {
+				op.params[key] = value
+			}
+		}
+
+		item := "message " + params["ts"]
+		if len(params["file"]) > 0 {
+			item = "file " + params["file"]
+		} else if len(params["ts"]) == 0 {
+			item = "channel " + channel
+		}
+		switch op.cmd {
+		case "reactions.add":
+			op.desc = fmt.Sprintf("add :%s: back to %s", reactionName(params), item)
+		case "reactions.remove":
+			op.desc = fmt.Sprintf("remove :%s: from %s", reactionName(params), item)
+		case "pins.add":
+			op.desc = fmt.Sprintf("pin %s back to %s", item, channel)
+		case "pins.remove":
+			op.desc = fmt.Sprintf("unpin %s from %s", item, channel)
+		case "stars.add":
+			op.desc = fmt.Sprintf("star %s again", item)
+		case "stars.remove":
+			op.desc = fmt.Sprintf("unstar %s", item)
+		}
+		return op, nil
+	case "reminders.add":
+		// the id is filled in by the result
+		return &undoOp{cmd: "reminders.delete"}, nil
+	case "usergroups.disable", "usergroups.enable":
+		id, err := s.usergroupParam(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		op := &undoOp{cmd: "usergroups.enable", params: map[string]string{"usergroup": id}}
+		if cmd == "usergroups.enable" {
+			op.cmd = "usergroups.disable"
+		}
+		op.desc = fmt.Sprintf("%s user group %s", strings.TrimPrefix(op.cmd, "usergroups."), id)
+		return op, nil
+	case "usergroups.rotate", "usergroups.users.update":
+		var id string
+		var old []string
+		var err error
+		if cmd == "usergroups.rotate" {
+			// the members the confirmed diff was made from
+			id, old, _, err = s.rotation(ctx, params)
+		} else if id, err = s.usergroupParam(ctx, params); err == nil {
+			old, err = s.s.ListUsergroupUsers(ctx, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(old) == 0 {
+			return nil, nil
+		}
+
+		return &undoOp{
+			desc:   fmt.Sprintf("restore the %d users of user group %s", len(old), id),
+			cmd:    "usergroups.users.update",
+			params: map[string]string{"usergroup": id, "users": strings.Join(old, ",")},
+		}, nil
+	case "dnd.setsnooze":
+		return &undoOp{desc: "end the snooze", cmd: "dnd.endSnooze", params: map[string]string{}}, nil
+	case "dnd.endsnooze":
+		d, err := s.s.GetDNDInfo(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		// snooze_remaining is in seconds
+		minutes := (d.SnoozeRemaining + 59) / 60
+		if !d.SnoozeEnabled || minutes <= 0 {
+			return nil, nil
+		}
+		return &undoOp{
+			desc:   fmt.Sprintf("snooze again for %d minutes", minutes),
+			cmd:    "dnd.setSnooze",
+			params: map[string]string{"minutes": strconv.Itoa(minutes)},
+		}, nil
+	case "users.profile.set":
+		user, err := s.resolveUser(ctx, params["user"])
+		if err != nil {
+			return nil, err
+		}
+		old, err := s.profileParams(ctx, user, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		for key := range old {
+			if key != "user" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		return &undoOp{
+			desc:   fmt.Sprintf("restore %s of the profile", strings.Join(keys, ", ")),
+			cmd:    cmd,
+			params: old,
+		}, nil
+	case "channels.archive", "groups.archive", "conversations.archive":
+		return &undoOp{
+			desc:   fmt.Sprintf("unarchive %s", channel),
+			cmd:    tp + ".unarchive",
+			params: map[string]string{"channel": channel},
+		}, nil
+	case "channels.kick", "groups.kick":
+		return &undoOp{
+			desc:   fmt.Sprintf("invite %s back to %s", params["user"], channel),
+			cmd:    tp + ".invite",
+			params: map[string]string{"channel": channel, "user": params["user"]},
+		}, nil
+	case "conversations.kick":
+		return &undoOp{
+			desc:   fmt.Sprintf("invite %s back to %s", params["user"], channel),
+			cmd:    "conversations.invite",
+			params: map[string]string{"channel": channel, "users": params["user"]},
+		}, nil
+	case "channels.settopic", "groups.settopic", "conversations.settopic",
+		"channels.setpurpose", "groups.setpurpose", "conversations.setpurpose",
+		"channels.rename", "groups.rename", "conversations.rename":
+		stateOf := s.channelState
+		if tp == "conversations" {
+			stateOf = s.conversationState
+		}
+		name, topic, purpose, err := stateOf(ctx, channel)
+		if err != nil {
+			return nil, err
+		}
+
+		op := &undoOp{params: map[string]string{"channel": channel}}
+		switch strings.SplitN(cmd, ".", 2)[1] {
+		case "settopic":
+			op.desc = fmt.Sprintf("restore topic of %s to %q", channel, topic)
+			op.cmd = tp + ".setTopic"
+			op.params["topic"] = topic
+		case "setpurpose":
+			op.desc = fmt.Sprintf("restore purpose of %s to %q", channel, purpose)
+			op.cmd = tp + ".setPurpose"
+			op.params["purpose"] = purpose
+		case "rename":
+			op.desc = fmt.Sprintf("rename %s back to %s", channel, name)
+			op.cmd = tp + ".rename"
+			op.params["name"] = name
+		}
+		return op, nil
+	}
+
+	return nil, nil
+}
+
+// channelState returns the name, topic and purpose of a channel or group.
+func (s *Slack) channelState(ctx context.Context, id string) (string, string, string, error) {
+	if strings.HasPrefix(id, "G") {
+		g, err := s.getGroup(ctx, id)
+		if err != nil {
+			return "", "", "", err
+		}
+		return g.Name, g.Topic.Value, g.Purpose.Value, nil
+	}
+
+	ch, err := s.s.GetChannelInfo(ctx, id)
+	if err != nil {
+		return "", "", "", err
+	}
+	return ch.Name, ch.Topic.Value, ch.Purpose.Value, nil
+}
+
+// conversationState returns the name, topic and purpose of any conversation.
+func (s *Slack) conversationState(ctx context.Context, id string) (string, string, string, error) {
+	ch, err := s.s.GetConversationInfo(ctx, id)
+	if err != nil {
+		return "", "", "", err
+	}
+	return ch.Name, ch.Topic.Value, ch.Purpose.Value, nil
+}
+
+// getGroup finds a group by id, there is no groups.info.
+func (s *Slack) getGroup(ctx context.Context, id string) (*slack.Group, error) {
+	groups, err := s.s.GetGroups(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].Id == id {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("group %s not found", id)
+}
+
+// recordUndo remembers op once its command succeeded with result v.
+func (s *Slack) recordUndo(op *undoOp, v interface{}) {
+	if op.cmd == "chat.delete" {
+		m, ok := v.(map[string]string)
+		if !ok {
+			return
+		}
+		op.desc = fmt.Sprintf("delete message %s in %s", m["ts"], m["channel"])
+		op.params = map[string]string{"channel": m["channel"], "ts": m["ts"]}
+	} else if op.cmd == "reminders.delete" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		r, ok := m["reminder"].(*Reminder)
+		if !ok || r == nil {
+			return
+		}
+		op.desc = fmt.Sprintf("delete reminder %s %q", r.Id, truncate(r.Text, 40))
+		op.params = map[string]string{"reminder": r.Id}
+	}
+
+	s.undoOps = append(s.undoOps, op)
+	if len(s.undoOps) > maxUndo {
+		s.undoOps = s.undoOps[len(s.undoOps)-maxUndo:]
+	}
+}
+
+// undo reverts the last reversible command.
+func (s *Slack) undo() (interface{}, error) {
+	if len(s.undoOps) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	op := s.undoOps[len(s.undoOps)-1]
+	s.undoOps = s.undoOps[:len(s.undoOps)-1]
+
+	fmt.Printf("undo: %s\n", op.desc)
+
+	s.undoing = true
+	defer func() {
+		s.undoing = false
+	}()
+
+	v, err := s.runParams(op.cmd, op.params)
+	if err != nil {
+		// keep it, so undo can be tried again
+		s.undoOps = append(s.undoOps, op)
+	}
+	return v, err
+}
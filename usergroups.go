@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type UsergroupPrefs struct {
+	Channels []string `json:"channels"`
+	Groups   []string `json:"groups"`
+}
+
+// Usergroup is a group of users mentioned by its handle, like @oncall-db.
+type Usergroup struct {
+	Id          string         `json:"id"`
+	TeamId      string         `json:"team_id"`
+	Name        string         `json:"name"`
+	Handle      string         `json:"handle"`
+	Description string         `json:"description"`
+	DateCreate  int64          `json:"date_create"`
+	DateUpdate  int64          `json:"date_update"`
+	DateDelete  int64          `json:"date_delete"`
+	CreatedBy   string         `json:"created_by"`
+	UpdatedBy   string         `json:"updated_by"`
+	Prefs       UsergroupPrefs `json:"prefs"`
+	Users       []string       `json:"users,omitempty"`
+}
+
+// usergroupFields are the fields set by usergroups.create and usergroups.update,
+// empty ones are left out.
+type usergroupFields struct {
+	Name        string
+	Handle      string
+	Description string
+	Channels    []string
+}
+
+func (f usergroupFields) values() url.Values {
+	values := url.Values{}
+	if len(f.Name) > 0 {
+		values.Set("name", f.Name)
+	}
+	if len(f.Handle) > 0 {
+		values.Set("handle", f.Handle)
+	}
+	if len(f.Description) > 0 {
+		values.Set("description", f.Description)
+	}
+	if len(f.Channels) > 0 {
+		values.Set("channels", strings.Join(f.Channels, ","))
+	}
+	return values
+}
+
+// usergroup calls a method which returns the changed user group.
+func (c *apiClient) usergroup(ctx context.Context, method string, values url.Values) (*Usergroup, error) {
+	var r struct {
+		Usergroup *Usergroup `json:"usergroup"`
+	}
+	if err := c.call(ctx, method, values, &r); err != nil {
+		return nil, err
+	}
+	return r.Usergroup, nil
+}
+
+func (c *apiClient) CreateUsergroup(ctx context.Context, fields usergroupFields) (*Usergroup, error) {
+	return c.usergroup(ctx, "usergroups.create", fields.values())
+}
+
+func (c *apiClient) DisableUsergroup(ctx context.Context, id string) (*Usergroup, error) {
+	return c.usergroup(ctx, "usergroups.disable", url.Values{"usergroup": {id}})
+}
+
+func (c *apiClient) EnableUsergroup(ctx context.Context, id string) (*Usergroup, error) {
+	return c.usergroup(ctx, "usergroups.enable", url.Values{"usergroup": {id}})
+}
+
+func (c *apiClient) ListUsergroups(ctx context.Context, includeDisabled bool, includeUsers bool) ([]Usergroup, error) {
+	values := url.Values{}
+	if includeDisabled {
+		values.Set("include_disabled", "true")
+	}
+	if includeUsers {
+		values.Set("include_users", "true")
+	}
+
+	var r struct {
+		Usergroups []Usergroup `json:"usergroups"`
+	}
+	if err := c.call(ctx, "usergroups.list", values, &r); err != nil {
+		return nil, err
+	}
+	return r.Usergroups, nil
+}
+
+func (c *apiClient) ListUsergroupUsers(ctx context.Context, id string) ([]string, error) {
+	var r struct {
+		Users []string `json:"users"`
+	}
+	if err := c.call(ctx, "usergroups.users.list", url.Values{"usergroup": {id}, "include_disabled": {"true"}}, &r); err != nil {
+		return nil, err
+	}
+	return r.Users, nil
+}
+
+func (c *apiClient) UpdateUsergroup(ctx context.Context, id string, fields usergroupFields) (*Usergroup, error) {
+	values := fields.values()
+	values.Set("usergroup", id)
+	return c.usergroup(ctx, "usergroups.update", values)
+}
+
+// UpdateUsergroupUsers replaces all the users of a user group in one call.
+func (c *apiClient) UpdateUsergroupUsers(ctx context.Context, id string, users []string) (*Usergroup, error) {
+	return c.usergroup(ctx, "usergroups.users.update", url.Values{
+		"usergroup": {id},
+		"users":     {strings.Join(users, ",")},
+	})
+}
+
+// usergroupID is the shape of user group ids like S0614TZR7, a handle like SRE
+// is looked up.
+var usergroupID = regexp.MustCompile(`^S[0-9A-Z]*[0-9][0-9A-Z]*$`)
+
+// resolveUsergroup maps a user group id, handle or @handle to the id.
+func (s *Slack) resolveUsergroup(ctx context.Context, ref string) (string, error) {
+	if len(ref) == 0 {
+		return "", fmt.Errorf("usergroup is required")
+	}
+	if usergroupID.MatchString(ref) {
+		return ref, nil
+	}
+	handle := strings.TrimPrefix(ref, "@")
+
+	groups, err := s.s.ListUsergroups(ctx, true, false)
+	if err != nil {
+		return "", err
+	}
+	for _, g := range groups {
+		if g.Handle == handle {
+			return g.Id, nil
+		}
+	}
+	return "", fmt.Errorf("usergroup @%s not found", handle)
+}
+
+// usergroupParam is the user group of a command, usergroup=id or @handle, or
+// handle= for the commands which don't set the handle.
+func (s *Slack) usergroupParam(ctx context.Context, params map[string]string) (string, error) {
+	if ref, ok := params["usergroup"]; ok {
+		return s.resolveUsergroup(ctx, ref)
+	}
+	return s.resolveUsergroup(ctx, params["handle"])
+}
+
+func (s *Slack) usergroupFieldsParams(ctx context.Context, params map[string]string) (usergroupFields, error) {
+	fields := usergroupFields{
+		Name:        params["name"],
+		Handle:      strings.TrimPrefix(params["handle"], "@"),
+		Description: params["description"],
+	}
+	if len(params["channels"]) > 0 {
+		for _, ch := range strings.Split(params["channels"], ",") {
+			id, err := s.resolveChannel(ctx, strings.TrimSpace(ch))
+			if err != nil {
+				return fields, err
+			}
+			fields.Channels = append(fields.Channels, id)
+		}
+	}
+	return fields, nil
+}
+
+// membersDiff compares the users of a user group before and after a change.
+type membersDiff struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+func diffMembers(old []string, users []string) *membersDiff {
+	in := make(map[string]bool, len(old))
+	for _, u := range old {
+		in[u] = true
+	}
+
+	d := &membersDiff{Added: []string{}, Removed: []string{}, Unchanged: []string{}}
+	keep := make(map[string]bool, len(users))
+	for _, u := range users {
+		keep[u] = true
+		if in[u] {
+			d.Unchanged = append(d.Unchanged, u)
+		} else {
+			d.Added = append(d.Added, u)
+		}
+	}
+	for _, u := range old {
+		if !keep[u] {
+			d.Removed = append(d.Removed, u)
+		}
+	}
+	return d
+}
+
+// named replaces the user ids of d with @names, when the users can be listed.
+func (d *membersDiff) named(names map[string]string) *membersDiff {
+	name := func(ids []string) []string {
+		named := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if n, ok := names[id]; ok {
+				id = "@" + n
+			}
+			named = append(named, id)
+		}
+		sort.Strings(named)
+		return named
+	}
+	return &membersDiff{Added: name(d.Added), Removed: name(d.Removed), Unchanged: name(d.Unchanged)}
+}
+
+func (d *membersDiff) String() string {
+	var lines []string
+	for _, u := range d.Added {
+		lines = append(lines, "+ "+u)
+	}
+	for _, u := range d.Removed {
+		lines = append(lines, "- "+u)
+	}
+	for _, u := range d.Unchanged {
+		lines = append(lines, "  "+u)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rotationPlan is a rotate command worked out once, so the diff which is
+// confirmed is the one applied and undone.
+type rotationPlan struct {
+	key   string
+	id    string
+	old   []string
+	users []string
+}
+
+// rotation returns the user group of a rotate command, its current users and
+// the new ones. The current users are fetched once per command line.
+func (s *Slack) rotation(ctx context.Context, params map[string]string) (string, []string, []string, error) {
+	id, err := s.usergroupParam(ctx, params)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(params["users"]) == 0 {
+		return "", nil, nil, fmt.Errorf("users is required, a user group can't be empty")
+	}
+	users, err := s.resolveUsers(ctx, params["users"])
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	key := id + " " + strings.Join(users, ",")
+	if p := s.rotationPlan; p != nil && p.key == key {
+		return p.id, p.old, p.users, nil
+	}
+
+	old, err := s.s.ListUsergroupUsers(ctx, id)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	s.rotationPlan = &rotationPlan{key: key, id: id, old: old, users: users}
+	return id, old, users, nil
+}
+
+func (s *Slack) handleUsergroups(ctx context.Context, action string, params map[string]string) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch action {
+	case "create":
+		fields, err := s.usergroupFieldsParams(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields.Name) == 0 {
+			return nil, fmt.Errorf("name is required")
+		}
+
+		g, err := s.s.CreateUsergroup(ctx, fields)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"usergroup": g,
+		}
+	case "disable", "enable":
+		id, err := s.usergroupParam(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var g *Usergroup
+		if action == "disable" {
+			g, err = s.s.DisableUsergroup(ctx, id)
+		} else {
+			g, err = s.s.EnableUsergroup(ctx, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"usergroup": g,
+		}
+	case "list":
+		groups, err := s.s.ListUsergroups(ctx, getBoolParam(params, "include_disabled", false), getBoolParam(params, "include_users", false))
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"usergroups": groups,
+		}
+	case "rotate":
+		id, old, users, err := s.rotation(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		s.rotationPlan = nil
+
+		if _, err = s.s.UpdateUsergroupUsers(ctx, id, users); err != nil {
+			return nil, err
+		}
+		names, _ := s.userNames(ctx)
+		v = map[string]interface{}{
+			"usergroup": id,
+			"diff":      diffMembers(old, users).named(names),
+		}
+	case "update":
+		id, err := s.resolveUsergroup(ctx, params["usergroup"])
+		if err != nil {
+			return nil, err
+		}
+		fields, err := s.usergroupFieldsParams(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		g, err := s.s.UpdateUsergroup(ctx, id, fields)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"usergroup": g,
+		}
+	case "users.list":
+		id, err := s.usergroupParam(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		users, err := s.s.ListUsergroupUsers(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"users": users,
+		}
+	case "users.update":
+		id, err := s.usergroupParam(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		users, err := s.resolveUsers(ctx, params["users"])
+		if err != nil {
+			return nil, err
+		}
+
+		g, err := s.s.UpdateUsergroupUsers(ctx, id, users)
+		if err != nil {
+			return nil, err
+		}
+		v = map[string]interface{}{
+			"usergroup": g,
+		}
+	default:
+		return nil, fmt.Errorf("invalid usergroups action %s", action)
+	}
+
+	return v, err
+}